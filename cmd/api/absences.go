@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/annusingmar/lavurso-backend/internal/data"
+	"github.com/annusingmar/lavurso-backend/internal/policy"
 	"github.com/annusingmar/lavurso-backend/internal/validator"
 	"github.com/go-chi/chi/v5"
 )
@@ -46,16 +47,8 @@ func (app *application) excuseAbsenceForStudent(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	if *sessionUser.Role != data.RoleAdministrator {
-		ok, err := app.models.Users.IsUserTeacherOrParentOfStudent(*mark.UserID, sessionUser.ID)
-		if err != nil {
-			app.writeInternalServerError(w, r, err)
-			return
-		}
-		if !ok {
-			app.notAllowed(w, r)
-			return
-		}
+	if !app.authorize(w, r, policy.StudentExcuse, app.isTeacherOrParentOfStudent(sessionUser, *mark.UserID)) {
+		return
 	}
 
 	at := time.Now().UTC()
@@ -120,16 +113,8 @@ func (app *application) deleteExcuseForStudent(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	if *sessionUser.Role != data.RoleAdministrator {
-		ok, err := app.models.Users.IsUserTeacherOrParentOfStudent(*mark.UserID, sessionUser.ID)
-		if err != nil {
-			app.writeInternalServerError(w, r, err)
-			return
-		}
-		if !ok {
-			app.notAllowed(w, r)
-			return
-		}
+	if !app.authorize(w, r, policy.StudentExcuse, app.isTeacherOrParentOfStudent(sessionUser, *mark.UserID)) {
+		return
 	}
 
 	err = app.models.Absences.DeleteExcuseByMarkID(mark.ID)
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/annusingmar/lavurso-backend/internal/data"
+	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/go-chi/chi/v5"
+)
+
+// retentionEnded reports whether the archived user's data no longer has to be kept
+func (config anonymization) retentionEnded(user *data.User) bool {
+	if !*user.Archived || user.ArchivedAt == nil {
+		return false
+	}
+
+	period := config.RetentionPeriod
+	if p, ok := config.RetentionPeriods[*user.Role]; ok {
+		period = p
+	}
+
+	return time.Since(*user.ArchivedAt) >= period
+}
+
+func (app *application) anonymizeUser(w http.ResponseWriter, r *http.Request) {
+	sessionUser := app.getUserFromContext(r)
+
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if userID < 0 || err != nil {
+		app.writeErrorResponse(w, r, http.StatusNotFound, data.ErrNoSuchUser.Error())
+		return
+	}
+
+	user, err := app.models.Users.GetUserByID(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoSuchUser):
+			app.writeErrorResponse(w, r, http.StatusNotFound, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	if !*user.Archived {
+		app.writeErrorResponse(w, r, http.StatusConflict, data.ErrUserNotArchived.Error())
+		return
+	}
+
+	if !app.config.Anonymization.retentionEnded(&user.User) {
+		app.writeErrorResponse(w, r, http.StatusConflict, data.ErrRetentionNotEnded.Error())
+		return
+	}
+
+	err = app.models.Anonymizations.AnonymizeUser(&user.User, &sessionUser.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrUserAnonymized):
+			app.writeErrorResponse(w, r, http.StatusConflict, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	if log := app.getLogFromContext(r); log != nil {
+		log.Event = helpers.ToPtr(data.LogEventAnonymize)
+	}
+
+	err = app.outputJSON(w, http.StatusOK, envelope{"message": "success"})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
+
+func (app *application) listAnonymizations(w http.ResponseWriter, r *http.Request) {
+	anonymizations, err := app.models.Anonymizations.GetAnonymizations()
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.outputJSON(w, http.StatusOK, envelope{"anonymizations": anonymizations})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
+
+// anonymizeExpiredUsers anonymizes all archived users whose retention period has ended
+func (app *application) anonymizeExpiredUsers() error {
+	users, err := app.models.Anonymizations.GetAnonymizationCandidates()
+	if err != nil {
+		return err
+	}
+
+	var anonymized int
+
+	for _, user := range users {
+		if !app.config.Anonymization.retentionEnded(user) {
+			continue
+		}
+
+		err = app.models.Anonymizations.AnonymizeUser(user, nil)
+		if err != nil {
+			// another run got to the user first
+			if errors.Is(err, data.ErrUserAnonymized) {
+				continue
+			}
+			return err
+		}
+
+		anonymized++
+	}
+
+	app.infoLogger.Printf("anonymize: %d users anonymized", anonymized)
+
+	return nil
+}
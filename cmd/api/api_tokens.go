@@ -0,0 +1,285 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/annusingmar/lavurso-backend/internal/data"
+	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/annusingmar/lavurso-backend/internal/types"
+	"github.com/annusingmar/lavurso-backend/internal/validator"
+	"github.com/go-chi/chi/v5"
+)
+
+// apiTokenRouteResources maps path segments to the resource whose scope a route requires,
+// the last mapped segment of the route pattern decides
+var apiTokenRouteResources = map[string]string{
+	"users":          "users",
+	"me":             "users",
+	"students":       "users",
+	"parents":        "users",
+	"teachers":       "users",
+	"identities":     "users",
+	"invitations":    "users",
+	"anonymizations": "users",
+	"classes":        "classes",
+	"subjects":       "subjects",
+	"grades":         "grades",
+	"groups":         "groups",
+	"journals":       "journals",
+	"courses":        "journals",
+	"lessons":        "lessons",
+	"assignments":    "assignments",
+	"done":           "assignments",
+	"marks":          "marks",
+	"latest":         "marks",
+	"absences":       "absences",
+	"excuse":         "absences",
+	"threads":        "messages",
+	"messages":       "messages",
+	"members":        "messages",
+	"unread":         "messages",
+	"years":          "years",
+	"sessions":       "sessions",
+	"logs":           "logs",
+	"lockouts":       "logs",
+}
+
+// apiTokenDeniedSegments are in routes that manage the user's own credentials,
+// which API tokens can never be used for
+var apiTokenDeniedSegments = map[string]bool{
+	"2fa":            true,
+	"passkeys":       true,
+	"password":       true,
+	"tokens":         true,
+	"logout":         true,
+	"impersonate":    true,
+	"impersonations": true,
+}
+
+// routeScope returns the scope an API token needs for the route,
+// ok is false if the route can't be used with API tokens
+func routeScope(method, pattern string) (string, bool) {
+	if pattern == "/me" && method != http.MethodGet {
+		return "", false
+	}
+
+	segments := strings.Split(pattern, "/")
+
+	var resource string
+	for i := len(segments) - 1; i >= 0; i-- {
+		if apiTokenDeniedSegments[segments[i]] {
+			return "", false
+		}
+		if resource == "" {
+			resource = apiTokenRouteResources[segments[i]]
+		}
+	}
+
+	if resource == "" {
+		return "", false
+	}
+
+	if method == http.MethodGet {
+		return resource + ":read", true
+	}
+
+	return resource + ":write", true
+}
+
+func (app *application) listAPITokens(w http.ResponseWriter, r *http.Request) {
+	sessionUser := app.getUserFromContext(r)
+
+	tokens, err := app.models.APITokens.GetAPITokensForUser(sessionUser.ID)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.outputJSON(w, http.StatusOK, envelope{"tokens": tokens})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
+
+func (app *application) createAPIToken(w http.ResponseWriter, r *http.Request) {
+	sessionUser := app.getUserFromContext(r)
+
+	app.insertAPIToken(w, r, sessionUser.ID)
+}
+
+func (app *application) deleteAPIToken(w http.ResponseWriter, r *http.Request) {
+	sessionUser := app.getUserFromContext(r)
+
+	tokenID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if tokenID < 0 || err != nil {
+		app.writeErrorResponse(w, r, http.StatusNotFound, data.ErrNoSuchAPIToken.Error())
+		return
+	}
+
+	app.removeAPIToken(w, r, sessionUser.ID, tokenID)
+}
+
+func (app *application) getAPITokensForUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if userID < 0 || err != nil {
+		app.writeErrorResponse(w, r, http.StatusNotFound, data.ErrNoSuchUser.Error())
+		return
+	}
+
+	user, err := app.models.Users.GetUserByID(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoSuchUser):
+			app.writeErrorResponse(w, r, http.StatusNotFound, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	tokens, err := app.models.APITokens.GetAPITokensForUser(user.ID)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.outputJSON(w, http.StatusOK, envelope{"tokens": tokens})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
+
+func (app *application) createAPITokenForUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if userID < 0 || err != nil {
+		app.writeErrorResponse(w, r, http.StatusNotFound, data.ErrNoSuchUser.Error())
+		return
+	}
+
+	user, err := app.models.Users.GetUserByID(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoSuchUser):
+			app.writeErrorResponse(w, r, http.StatusNotFound, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	// other users create their own tokens, so that they can't be used to act as them
+	if !*user.ServiceAccount {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, data.ErrNotAServiceAccount.Error())
+		return
+	}
+
+	app.insertAPIToken(w, r, user.ID)
+}
+
+func (app *application) deleteAPITokenForUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "uid"))
+	if userID < 0 || err != nil {
+		app.writeErrorResponse(w, r, http.StatusNotFound, data.ErrNoSuchUser.Error())
+		return
+	}
+
+	tokenID, err := strconv.Atoi(chi.URLParam(r, "tid"))
+	if tokenID < 0 || err != nil {
+		app.writeErrorResponse(w, r, http.StatusNotFound, data.ErrNoSuchAPIToken.Error())
+		return
+	}
+
+	app.removeAPIToken(w, r, userID, tokenID)
+}
+
+// insertAPIToken creates a token for the user from the request
+// and responds with its plaintext value, which is only shown once
+func (app *application) insertAPIToken(w http.ResponseWriter, r *http.Request, userID int) {
+	sessionUser := app.getUserFromContext(r)
+
+	var input struct {
+		Name    string    `json:"name"`
+		Scopes  []string  `json:"scopes"`
+		Expires time.Time `json:"expires"`
+	}
+
+	err := app.inputJSON(w, r, &input)
+	if err != nil {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	v := validator.NewValidator()
+
+	v.Check(input.Name != "", "name", "must be provided")
+	v.Check(len(input.Scopes) > 0, "scopes", "must be provided")
+	for _, scope := range input.Scopes {
+		v.Check(data.ValidAPITokenScope(scope), "scopes", "must be valid scopes")
+	}
+	v.Check(input.Expires.After(time.Now()), "expires", "must be in the future")
+	v.Check(input.Expires.Before(time.Now().Add(data.APITokenMaxLifetime)), "expires", "must be within a year")
+
+	if !v.Valid() {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	token := &data.APIToken{
+		UserID:    &userID,
+		Name:      &input.Name,
+		Token:     new(types.Token),
+		Scopes:    helpers.ToPtr(types.Scopes(input.Scopes)),
+		CreatedBy: &sessionUser.ID,
+		Expires:   helpers.ToPtr(input.Expires.UTC()),
+	}
+
+	err = token.Token.NewToken()
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.models.APITokens.InsertAPIToken(token)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.outputJSON(w, http.StatusCreated, envelope{"token": token, "value": data.APITokenPrefix + token.Token.Plaintext})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
+
+func (app *application) removeAPIToken(w http.ResponseWriter, r *http.Request, userID, tokenID int) {
+	token, err := app.models.APITokens.GetAPITokenByID(tokenID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoSuchAPIToken):
+			app.writeErrorResponse(w, r, http.StatusNotFound, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	if *token.UserID != userID {
+		app.writeErrorResponse(w, r, http.StatusNotFound, data.ErrNoSuchAPIToken.Error())
+		return
+	}
+
+	err = app.models.APITokens.DeleteAPIToken(token.ID)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.outputJSON(w, http.StatusOK, envelope{"message": "success"})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
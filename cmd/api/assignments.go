@@ -8,6 +8,7 @@ import (
 
 	"github.com/annusingmar/lavurso-backend/internal/data"
 	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/annusingmar/lavurso-backend/internal/policy"
 	"github.com/annusingmar/lavurso-backend/internal/types"
 	"github.com/annusingmar/lavurso-backend/internal/validator"
 	"github.com/go-chi/chi/v5"
@@ -49,8 +50,7 @@ func (app *application) getAssignment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !journal.IsUserTeacherOfJournal(sessionUser.ID) && *sessionUser.Role != data.RoleAdministrator {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.JournalView, isTeacherOfJournal(sessionUser, journal)) {
 		return
 	}
 
@@ -115,8 +115,7 @@ func (app *application) createAssignment(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if !journal.IsUserTeacherOfJournal(sessionUser.ID) && *sessionUser.Role != data.RoleAdministrator {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.JournalEdit, isTeacherOfJournal(sessionUser, journal)) {
 		return
 	}
 
@@ -170,8 +169,7 @@ func (app *application) updateAssignment(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if !journal.IsUserTeacherOfJournal(sessionUser.ID) && *sessionUser.Role != data.RoleAdministrator {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.JournalEdit, isTeacherOfJournal(sessionUser, journal)) {
 		return
 	}
 
@@ -264,8 +262,7 @@ func (app *application) deleteAssignment(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if !journal.IsUserTeacherOfJournal(sessionUser.ID) && *sessionUser.Role != data.RoleAdministrator {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.JournalEdit, isTeacherOfJournal(sessionUser, journal)) {
 		return
 	}
 
@@ -312,8 +309,7 @@ func (app *application) getAssignmentsForJournal(w http.ResponseWriter, r *http.
 		return
 	}
 
-	if !journal.IsUserTeacherOfJournal(sessionUser.ID) && *sessionUser.Role != data.RoleAdministrator {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.JournalView, isTeacherOfJournal(sessionUser, journal)) {
 		return
 	}
 
@@ -349,16 +345,8 @@ func (app *application) getAssignmentsForStudent(w http.ResponseWriter, r *http.
 		return
 	}
 
-	if sessionUser.ID != student.ID && *sessionUser.Role != data.RoleAdministrator {
-		ok, err := app.models.Users.IsUserTeacherOrParentOfStudent(student.ID, sessionUser.ID)
-		if err != nil {
-			app.writeInternalServerError(w, r, err)
-			return
-		}
-		if !ok {
-			app.notAllowed(w, r)
-			return
-		}
+	if !app.authorize(w, r, policy.StudentView, app.isRelatedToStudent(sessionUser, student.ID)) {
+		return
 	}
 
 	var from *types.Date
@@ -420,8 +408,7 @@ func (app *application) setAssignmentDoneForStudent(w http.ResponseWriter, r *ht
 		return
 	}
 
-	if sessionUser.ID != userID {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.AssignmentMarkDone, isUser(sessionUser, userID)) {
 		return
 	}
 
@@ -478,8 +465,7 @@ func (app *application) removeAssignmentDoneForStudent(w http.ResponseWriter, r
 		return
 	}
 
-	if sessionUser.ID != userID {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.AssignmentMarkDone, isUser(sessionUser, userID)) {
 		return
 	}
 
@@ -1,25 +1,33 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/annusingmar/lavurso-backend/internal/data"
 	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/annusingmar/lavurso-backend/internal/mailer"
 	"github.com/annusingmar/lavurso-backend/internal/types"
+	"github.com/annusingmar/lavurso-backend/internal/useragent"
 	"github.com/annusingmar/lavurso-backend/internal/validator"
 )
 
 var (
 	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrExternalPassword   = errors.New("password is managed by the school's directory")
 )
 
 func (app *application) authenticateUser(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
-		OTP      *int   `json:"otp"`
+		Email        string          `json:"email"`
+		Password     string          `json:"password"`
+		OTP          *int            `json:"otp"`
+		RecoveryCode *string         `json:"recovery_code"`
+		Passkey      json.RawMessage `json:"passkey"`
+		RememberMe   bool            `json:"remember_me"`
 	}
 
 	err := app.inputJSON(w, r, &input)
@@ -38,10 +46,20 @@ func (app *application) authenticateUser(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	ip := app.getIP(r)
+
 	user, err := app.models.Users.GetUserByEmail(input.Email)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrNoSuchUser):
+			if !app.checkLoginAllowed(w, r, nil, ip) {
+				return
+			}
+			err = app.registerFailedLogin(nil, ip, r)
+			if err != nil {
+				app.writeInternalServerError(w, r, err)
+				return
+			}
 			app.writeErrorResponse(w, r, http.StatusForbidden, ErrInvalidCredentials.Error())
 		default:
 			app.writeInternalServerError(w, r, err)
@@ -49,63 +67,382 @@ func (app *application) authenticateUser(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	correct, err := user.Password.Validate(input.Password)
+	if !app.checkLoginAllowed(w, r, &user.ID, ip) {
+		return
+	}
+
+	var correct bool
+	switch {
+	case *user.ServiceAccount:
+		// service accounts only authenticate with API tokens
+		correct = false
+	case *user.ExternalAuth:
+		correct, err = app.validateExternalPassword(user, input.Password)
+	default:
+		correct, err = user.Password.Validate(input.Password)
+	}
 	if err != nil {
 		app.writeInternalServerError(w, r, err)
 		return
 	}
 
 	if !correct {
+		err = app.registerFailedLogin(&user.ID, ip, r)
+		if err != nil {
+			app.writeInternalServerError(w, r, err)
+			return
+		}
 		app.writeErrorResponse(w, r, http.StatusForbidden, ErrInvalidCredentials.Error())
 		return
 	}
 
-	if *user.TotpEnabled {
-		if input.OTP == nil {
-			app.writeErrorResponse(w, r, http.StatusForbidden, data.ErrMissingOTP.Error())
+	if !app.checkSecondFactor(w, r, user, ip, input.OTP, input.RecoveryCode, input.Passkey) {
+		return
+	}
+
+	err = app.models.Lockouts.DeleteFailedLoginsForUser(user.ID)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	// bcrypt and outdated argon2id hashes are replaced while the plaintext password is known
+	if !*user.ExternalAuth && user.Password.NeedsRehash() {
+		user.Password.Plaintext = input.Password
+		err = user.Password.CreateHash()
+		if err != nil {
+			app.writeInternalServerError(w, r, err)
+			return
+		}
+
+		err = app.models.Users.UpdatePassword(user.ID, user.Password)
+		if err != nil {
+			app.writeInternalServerError(w, r, err)
 			return
-		} else {
-			ok, err := user.TotpSecret.Validate(*input.OTP)
+		}
+	}
+
+	response, err := app.createSession(w, r, user, input.RememberMe)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.outputJSON(w, http.StatusAccepted, response)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
+
+// checkSecondFactor writes an error response and returns false if the user has 2FA enabled
+// and no valid OTP, recovery code or passkey was given
+func (app *application) checkSecondFactor(w http.ResponseWriter, r *http.Request, user *data.UserExt, ip string, otp *int, recoveryCode *string, passkey json.RawMessage) bool {
+	if !*user.TotpEnabled {
+		return true
+	}
+
+	switch {
+	case otp != nil:
+		ok, err := app.validateTOTP(user.ID, user.TotpSecret, user.TotpLastStep, *otp)
+		if err != nil {
+			app.writeInternalServerError(w, r, err)
+			return false
+		}
+		if !ok {
+			err = app.registerFailedLogin(&user.ID, ip, r)
 			if err != nil {
 				app.writeInternalServerError(w, r, err)
-				return
+				return false
 			}
-			if !ok {
-				app.writeErrorResponse(w, r, http.StatusForbidden, data.ErrInvalidOTP.Error())
+			app.writeErrorResponse(w, r, http.StatusForbidden, data.ErrInvalidOTP.Error())
+			return false
+		}
+	case recoveryCode != nil:
+		err := app.models.RecoveryCodes.UseRecoveryCode(user.ID, *recoveryCode)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrInvalidRecoveryCode):
+				err = app.registerFailedLogin(&user.ID, ip, r)
+				if err != nil {
+					app.writeInternalServerError(w, r, err)
+					return false
+				}
+				app.writeErrorResponse(w, r, http.StatusForbidden, data.ErrInvalidRecoveryCode.Error())
+			default:
+				app.writeInternalServerError(w, r, err)
+			}
+			return false
+		}
+	case len(passkey) > 0:
+		passkeyUserID, ok, err := app.validatePasskey(passkey)
+		if err != nil {
+			app.writeInternalServerError(w, r, err)
+			return false
+		}
+		if !ok || passkeyUserID != user.ID {
+			err = app.registerFailedLogin(&user.ID, ip, r)
+			if err != nil {
+				app.writeInternalServerError(w, r, err)
+				return false
+			}
+			app.writeErrorResponse(w, r, http.StatusForbidden, data.ErrInvalidPasskey.Error())
+			return false
+		}
+	default:
+		app.writeErrorResponse(w, r, http.StatusForbidden, data.ErrMissingOTP.Error())
+		return false
+	}
+
+	return true
+}
+
+func (app *application) refreshSession(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	// browser clients in the cookie modes send the refresh token as a cookie instead
+	// and the CSRF token with it, as a forged refresh would make the client's token count as reused
+	cookie, err := r.Cookie(app.config.Cookies.refreshName())
+	if app.config.Cookies.enabled() && err == nil {
+		if !app.validCSRF(r) {
+			app.writeErrorResponse(w, r, http.StatusForbidden, ErrInvalidCSRFToken.Error())
+			return
+		}
+		input.RefreshToken = cookie.Value
+	} else {
+		err = app.inputJSON(w, r, &input)
+		if err != nil {
+			app.writeErrorResponse(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	v := validator.NewValidator()
+
+	v.Check(input.RefreshToken != "", "refresh_token", "must be provided")
+
+	if !v.Valid() {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	old, err := app.models.Sessions.GetRefreshToken(input.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInvalidToken):
+			app.writeErrorResponse(w, r, http.StatusUnauthorized, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	if old.Family.RevokedAt != nil || old.Expires.Before(time.Now().UTC()) {
+		app.writeErrorResponse(w, r, http.StatusUnauthorized, data.ErrInvalidToken.Error())
+		return
+	}
+
+	// a used token being presented again means it was most likely stolen,
+	// so everything issued from the same login is revoked
+	if old.UsedAt != nil {
+		err = app.models.Sessions.RevokeSessionFamily(old.Family.ID)
+		if err != nil {
+			app.writeInternalServerError(w, r, err)
+			return
+		}
+		app.writeErrorResponse(w, r, http.StatusUnauthorized, data.ErrRefreshTokenReused.Error())
+		return
+	}
+
+	user, err := app.models.Users.GetUserByID(*old.Family.UserID)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	if !*user.Active || *user.Archived {
+		app.writeErrorResponse(w, r, http.StatusUnauthorized, data.ErrInvalidToken.Error())
+		return
+	}
+
+	session := app.newSession(r, user.ID, &old.Family.ID)
+
+	err = session.Token.NewToken()
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	refreshToken, err := app.newRefreshToken(old.Family.ID)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.models.Sessions.RotateRefreshToken(old, session, refreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRefreshTokenReused):
+			err = app.models.Sessions.RevokeSessionFamily(old.Family.ID)
+			if err != nil {
+				app.writeInternalServerError(w, r, err)
 				return
 			}
+			app.writeErrorResponse(w, r, http.StatusUnauthorized, data.ErrRefreshTokenReused.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
 		}
+		return
 	}
 
-	ip := app.getIP(r)
+	response, err := app.sessionResponse(w, session, refreshToken)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.outputJSON(w, http.StatusAccepted, response)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
 
+func (app *application) newSession(r *http.Request, userID int, familyID *int) *data.Session {
 	currentTime := time.Now().UTC()
 
-	session := &data.Session{
-		UserID:       &user.ID,
+	expires := currentTime.Add(app.config.Sessions.IdleTimeout)
+	if app.config.Sessions.AbsoluteLifetime > 0 && app.config.Sessions.AbsoluteLifetime < app.config.Sessions.IdleTimeout {
+		expires = currentTime.Add(app.config.Sessions.AbsoluteLifetime)
+	}
+
+	return &data.Session{
+		UserID:       &userID,
 		Token:        new(types.Token),
-		Expires:      helpers.ToPtr(currentTime.Add(3 * time.Minute)),
-		LoginIP:      &ip,
+		Expires:      &expires,
+		LoginIP:      helpers.ToPtr(app.getIP(r)),
 		LoginBrowser: helpers.ToPtr(r.UserAgent()),
 		LoggedIn:     &currentTime,
 		LastSeen:     &currentTime,
+		FamilyID:     familyID,
+		NewDevice:    helpers.ToPtr(false),
+	}
+}
+
+// isNewDevice reports whether the user has logged in before, but never with
+// the same browser and system. Version updates don't make a device new.
+func (app *application) isNewDevice(userID int, userAgent string) (bool, error) {
+	browsers, err := app.models.Sessions.GetLoginBrowsersByUserID(userID)
+	if err != nil {
+		return false, err
+	}
+
+	if len(browsers) == 0 {
+		return false, nil
+	}
+
+	agent := useragent.Parse(userAgent)
+	for _, b := range browsers {
+		if agent.SameDevice(useragent.Parse(b)) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func (app *application) sendNewDeviceMail(user *data.UserExt, session *data.Session) {
+	app.sendMail(&mailer.Message{
+		To:      *user.Email,
+		Subject: "Lavurso login from a new device",
+		Body: fmt.Sprintf("Hello, %s!\n\nYour account was just logged in to from a device you haven't used before:\n\n"+
+			"%s\nIP address: %s\nTime: %s\n\n"+
+			"If this was you, you can ignore this email. Otherwise, change your password and log out of your other sessions.\n",
+			*user.Name, useragent.Parse(*session.LoginBrowser).Description(), *session.LoginIP, session.LoggedIn.Format(time.RFC1123)),
+	})
+}
+
+func (app *application) newRefreshToken(familyID int) (*data.RefreshToken, error) {
+	refreshToken := &data.RefreshToken{
+		Token:    new(types.Token),
+		FamilyID: &familyID,
+		Expires:  helpers.ToPtr(time.Now().UTC().Add(app.config.Sessions.RefreshTokenLifetime)),
 	}
 
+	err := refreshToken.Token.NewToken()
+	if err != nil {
+		return nil, err
+	}
+
+	return refreshToken, nil
+}
+
+// createSession starts a new session for an already authenticated user,
+// with a refresh token if rememberMe is set and refresh tokens are enabled
+func (app *application) createSession(w http.ResponseWriter, r *http.Request, user *data.UserExt, rememberMe bool) (envelope, error) {
+	var familyID *int
+
+	rememberMe = rememberMe && app.config.Sessions.RefreshTokenLifetime > 0
+
+	if rememberMe {
+		family := &data.SessionFamily{UserID: &user.ID}
+
+		err := app.models.Sessions.InsertSessionFamily(family)
+		if err != nil {
+			return nil, err
+		}
+
+		familyID = &family.ID
+	}
+
+	session := app.newSession(r, user.ID, familyID)
+
+	newDevice, err := app.isNewDevice(user.ID, r.UserAgent())
+	if err != nil {
+		return nil, err
+	}
+	session.NewDevice = &newDevice
+
 	err = session.Token.NewToken()
 	if err != nil {
-		app.writeInternalServerError(w, r, err)
-		return
+		return nil, err
 	}
 
 	err = app.models.Sessions.InsertSession(session)
 	if err != nil {
-		app.writeInternalServerError(w, r, err)
-		return
+		return nil, err
+	}
+
+	if newDevice && app.config.Sessions.NewDeviceEmails {
+		app.sendNewDeviceMail(user, session)
 	}
 
-	err = app.outputJSON(w, http.StatusAccepted, envelope{"session": session})
+	if !rememberMe {
+		return app.sessionResponse(w, session, nil)
+	}
+
+	refreshToken, err := app.newRefreshToken(*familyID)
 	if err != nil {
-		app.writeInternalServerError(w, r, err)
+		return nil, err
+	}
+
+	refreshToken.SessionID = &session.ID
+
+	err = app.models.Sessions.InsertRefreshToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return app.sessionResponse(w, session, refreshToken)
+}
+
+// validateTOTP checks the otp and records its time step,
+// so the same code can't be used again
+func (app *application) validateTOTP(userID int, secret *types.TOTPSecret, lastStep *int64, otp int) (bool, error) {
+	step, ok, err := secret.Validate(otp, app.config.TOTP.Skew, lastStep)
+	if err != nil || !ok {
+		return false, err
 	}
 
+	return app.models.Users.UseTOTPStep(userID, step)
 }
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/annusingmar/lavurso-backend/internal/data"
+	"github.com/annusingmar/lavurso-backend/internal/types"
+	"github.com/annusingmar/lavurso-backend/internal/validator"
+	"github.com/go-chi/chi/v5"
+)
+
+func (app *application) getClassMembershipsForStudent(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if userID < 0 || err != nil {
+		app.writeErrorResponse(w, r, http.StatusNotFound, data.ErrNoSuchUser.Error())
+		return
+	}
+
+	student, err := app.models.Users.GetStudentByID(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoSuchUser):
+			app.writeErrorResponse(w, r, http.StatusNotFound, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	memberships, err := app.models.ClassMemberships.GetMembershipsForStudent(student.ID)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.outputJSON(w, http.StatusOK, envelope{"memberships": memberships})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
+
+// transferStudent moves the student to another class from the given date,
+// in the current year unless 'year_id' is given
+func (app *application) transferStudent(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if userID < 0 || err != nil {
+		app.writeErrorResponse(w, r, http.StatusNotFound, data.ErrNoSuchUser.Error())
+		return
+	}
+
+	student, err := app.models.Users.GetStudentByID(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoSuchUser):
+			app.writeErrorResponse(w, r, http.StatusNotFound, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		ClassID int        `json:"class_id"`
+		YearID  *int       `json:"year_id"`
+		Date    types.Date `json:"date"`
+	}
+
+	err = app.inputJSON(w, r, &input)
+	if err != nil {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	v := validator.NewValidator()
+
+	v.Check(input.ClassID > 0, "class_id", "must be provided")
+	v.Check(input.Date.Time != nil, "date", "must be provided")
+	v.Check(input.Date.Time == nil || !input.Date.Time.After(time.Now().UTC()), "date", "must not be in the future")
+
+	if !v.Valid() {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	class, err := app.models.Classes.GetClassByID(input.ClassID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoSuchClass):
+			app.writeErrorResponse(w, r, http.StatusBadRequest, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	var year *data.Year
+	if input.YearID != nil {
+		year, err = app.models.Years.GetYearByID(*input.YearID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoSuchYear):
+				app.writeErrorResponse(w, r, http.StatusBadRequest, err.Error())
+			default:
+				app.writeInternalServerError(w, r, err)
+			}
+			return
+		}
+	} else {
+		current, err := app.models.Years.GetCurrentYear()
+		if err != nil {
+			app.writeInternalServerError(w, r, err)
+			return
+		}
+		if current == nil {
+			app.writeErrorResponse(w, r, http.StatusBadRequest, data.ErrNoCurrentYear.Error())
+			return
+		}
+		year = &current.Year
+	}
+
+	err = app.models.ClassMemberships.TransferStudent(student.ID, class.ID, year, &input.Date)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrClassNotInYear) || errors.Is(err, data.ErrInvalidTransferDate):
+			app.writeErrorResponse(w, r, http.StatusBadRequest, err.Error())
+		case errors.Is(err, data.ErrAlreadyInClass):
+			app.writeErrorResponse(w, r, http.StatusConflict, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	err = app.outputJSON(w, http.StatusOK, envelope{"message": "success"})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
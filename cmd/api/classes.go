@@ -8,18 +8,17 @@ import (
 
 	"github.com/annusingmar/lavurso-backend/internal/data"
 	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/annusingmar/lavurso-backend/internal/policy"
 	"github.com/annusingmar/lavurso-backend/internal/validator"
 	"github.com/go-chi/chi/v5"
 )
 
 func (app *application) listAllClasses(w http.ResponseWriter, r *http.Request) {
-	sessionUser := app.getUserFromContext(r)
-
 	var err error
 	var classes []*data.ClassExt
 
 	current := r.URL.Query().Get("current")
-	if *sessionUser.Role != data.RoleAdministrator || current != "false" {
+	if !app.can(r, policy.ClassManage) || current != "false" {
 		classes, err = app.models.Classes.AllClasses(true)
 	} else {
 		classes, err = app.models.Classes.AllClasses(false)
@@ -45,8 +44,7 @@ func (app *application) getClassesForTeacher(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if teacherID != sessionUser.ID && *sessionUser.Role != data.RoleAdministrator {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.TeacherView, isUser(sessionUser, teacherID)) {
 		return
 	}
 
@@ -61,7 +59,7 @@ func (app *application) getClassesForTeacher(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if *teacher.Role != data.RoleAdministrator && *teacher.Role != data.RoleTeacher {
+	if app.policy.Check(*teacher.Roles, policy.JournalEdit) == policy.Denied {
 		app.writeErrorResponse(w, r, http.StatusBadRequest, "user not an admin")
 		return
 	}
@@ -230,6 +228,8 @@ func (app *application) updateClass(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// getStudentsInClass lists the students currently in the class,
+// or with query param 'year' the students who were in it at any time during that year
 func (app *application) getStudentsInClass(w http.ResponseWriter, r *http.Request) {
 	sessionUser := app.getUserFromContext(r)
 
@@ -250,25 +250,32 @@ func (app *application) getStudentsInClass(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if *sessionUser.Role != data.RoleAdministrator {
-		ok, err := app.models.Users.IsUserTeacherOfClass(sessionUser.ID, class.ID)
+	if !app.authorize(w, r, policy.ClassViewStudents, app.isTeacherOfClass(sessionUser, class.ID)) {
+		return
+	}
+
+	var users []*data.UserExt
+
+	if yearParam := r.URL.Query().Get("year"); yearParam != "" {
+		year, err := strconv.Atoi(yearParam)
+		if year < 1 || err != nil {
+			app.writeErrorResponse(w, r, http.StatusNotFound, "not valid year")
+			return
+		}
+
+		users, err = app.models.Classes.GetStudentsInClassForYear(class.ID, year)
 		if err != nil {
 			app.writeInternalServerError(w, r, err)
 			return
 		}
-
-		if !ok {
-			app.notAllowed(w, r)
+	} else {
+		users, err = app.models.Classes.GetUsersForClassID(class.ID)
+		if err != nil {
+			app.writeInternalServerError(w, r, err)
 			return
 		}
 	}
 
-	users, err := app.models.Classes.GetUsersForClassID(class.ID)
-	if err != nil {
-		app.writeInternalServerError(w, r, err)
-		return
-	}
-
 	err = app.outputJSON(w, http.StatusOK, envelope{"users": users})
 	if err != nil {
 		app.writeInternalServerError(w, r, err)
@@ -4,17 +4,33 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/BurntSushi/toml"
 )
 
 type configuration struct {
-	Web      web      `toml:"web"`
-	Database database `toml:"database"`
+	Web             web                 `toml:"web"`
+	Database        database            `toml:"database"`
+	Mail            mail                `toml:"mail"`
+	Passwords       passwords           `toml:"passwords"`
+	LoginProtection loginProtection     `toml:"login_protection"`
+	Sessions        sessions            `toml:"sessions"`
+	TOTP            totp                `toml:"totp"`
+	WebAuthn        webAuthn            `toml:"webauthn"`
+	OIDC            []oidcProvider      `toml:"oidc"`
+	LDAP            ldap                `toml:"ldap"`
+	Roles           map[string][]string `toml:"roles"`
+	Cookies         cookies             `toml:"cookies"`
+	Anonymization   anonymization       `toml:"anonymization"`
+	Search          search              `toml:"search"`
+	Years           years               `toml:"years"`
 }
 
 type web struct {
-	Listen string `toml:"listen"`
+	Listen         string   `toml:"listen"`
+	FrontendURL    string   `toml:"frontend_url"`
+	TrustedProxies []string `toml:"trusted_proxies"`
 }
 
 type database struct {
@@ -25,11 +41,113 @@ type database struct {
 	Name     string `toml:"dbname"`
 }
 
+type mail struct {
+	Backend  string `toml:"backend"`
+	LogFile  string `toml:"log_file"`
+	Host     string `toml:"host"`
+	Port     int    `toml:"port"`
+	User     string `toml:"user"`
+	Password string `toml:"password"`
+	From     string `toml:"from"`
+}
+
+type passwords struct {
+	ResetTokenLifetime  time.Duration `toml:"reset_token_lifetime"`
+	MinLength           int           `toml:"min_length"`
+	BannedPasswordsFile string        `toml:"banned_passwords_file"`
+	History             int           `toml:"history"`
+	InvitationLifetime  time.Duration `toml:"invitation_lifetime"`
+}
+
+type loginProtection struct {
+	Window             time.Duration `toml:"window"`
+	FreeAttempts       int           `toml:"free_attempts"`
+	BackoffBase        time.Duration `toml:"backoff_base"`
+	BackoffMax         time.Duration `toml:"backoff_max"`
+	AccountMaxAttempts int           `toml:"account_max_attempts"`
+	IPMaxAttempts      int           `toml:"ip_max_attempts"`
+	LockoutDuration    time.Duration `toml:"lockout_duration"`
+}
+
+type sessions struct {
+	IdleTimeout              time.Duration `toml:"idle_timeout"`
+	AbsoluteLifetime         time.Duration `toml:"absolute_lifetime"`
+	RefreshTokenLifetime     time.Duration `toml:"refresh_token_lifetime"`
+	ImpersonationLifetime    time.Duration `toml:"impersonation_lifetime"`
+	ImpersonationAllowWrites bool          `toml:"impersonation_allow_writes"`
+	NewDeviceEmails          bool          `toml:"new_device_emails"`
+}
+
+type totp struct {
+	Issuer string `toml:"issuer"`
+	Skew   int    `toml:"skew"`
+}
+
+type webAuthn struct {
+	RPID          string        `toml:"rp_id"`
+	RPDisplayName string        `toml:"rp_display_name"`
+	RPOrigins     []string      `toml:"rp_origins"`
+	Timeout       time.Duration `toml:"timeout"`
+}
+
+type oidcProvider struct {
+	Name         string   `toml:"name"`
+	DisplayName  string   `toml:"display_name"`
+	Issuer       string   `toml:"issuer"`
+	ClientID     string   `toml:"client_id"`
+	ClientSecret string   `toml:"client_secret"`
+	RedirectURL  string   `toml:"redirect_url"`
+	Scopes       []string `toml:"scopes"`
+	MatchByEmail bool     `toml:"match_by_email"`
+}
+
+type ldap struct {
+	Enabled            bool              `toml:"enabled"`
+	URL                string            `toml:"url"`
+	StartTLS           bool              `toml:"start_tls"`
+	InsecureSkipVerify bool              `toml:"insecure_skip_verify"`
+	BindDN             string            `toml:"bind_dn"`
+	BindPassword       string            `toml:"bind_password"`
+	BaseDN             string            `toml:"base_dn"`
+	UserFilter         string            `toml:"user_filter"`
+	SyncFilter         string            `toml:"sync_filter"`
+	NameAttribute      string            `toml:"name_attribute"`
+	EmailAttribute     string            `toml:"email_attribute"`
+	RoleAttribute      string            `toml:"role_attribute"`
+	Roles              map[string]string `toml:"roles"`
+	DefaultRole        string            `toml:"default_role"`
+}
+
+type cookies struct {
+	Mode     string `toml:"mode"`
+	Name     string `toml:"name"`
+	Domain   string `toml:"domain"`
+	Secure   bool   `toml:"secure"`
+	SameSite string `toml:"same_site"`
+}
+
+type anonymization struct {
+	RetentionPeriod  time.Duration            `toml:"retention_period"`
+	RetentionPeriods map[string]time.Duration `toml:"retention_periods"`
+}
+
+type search struct {
+	MinLength           int     `toml:"min_length"`
+	SimilarityThreshold float64 `toml:"similarity_threshold"`
+	MaxResults          int     `toml:"max_results"`
+}
+
+type years struct {
+	RolloverUndoWindow time.Duration `toml:"rollover_undo_window"`
+}
+
 func parseConfig() configuration {
 	// default config
 	cfg := configuration{
 		web{
-			Listen: "127.0.0.1:8080",
+			Listen:         "127.0.0.1:8080",
+			FrontendURL:    "http://localhost:3000",
+			TrustedProxies: []string{"127.0.0.1", "::1"},
 		},
 		database{
 			Host:     "localhost",
@@ -38,6 +156,72 @@ func parseConfig() configuration {
 			Password: "password",
 			Name:     "database_name",
 		},
+		mail{
+			Backend: "log",
+			Host:    "localhost",
+			Port:    25,
+			From:    "lavurso@localhost",
+		},
+		passwords{
+			ResetTokenLifetime:  time.Hour,
+			MinLength:           8,
+			BannedPasswordsFile: "",
+			History:             5,
+			InvitationLifetime:  7 * 24 * time.Hour,
+		},
+		loginProtection{
+			Window:             15 * time.Minute,
+			FreeAttempts:       3,
+			BackoffBase:        time.Second,
+			BackoffMax:         time.Minute,
+			AccountMaxAttempts: 10,
+			IPMaxAttempts:      50,
+			LockoutDuration:    30 * time.Minute,
+		},
+		sessions{
+			IdleTimeout:              30 * time.Minute,
+			AbsoluteLifetime:         24 * time.Hour,
+			RefreshTokenLifetime:     30 * 24 * time.Hour,
+			ImpersonationLifetime:    30 * time.Minute,
+			ImpersonationAllowWrites: false,
+			NewDeviceEmails:          false,
+		},
+		totp{
+			Issuer: "Lavurso",
+			Skew:   1,
+		},
+		webAuthn{
+			RPID:          "localhost",
+			RPDisplayName: "Lavurso",
+			RPOrigins:     []string{"http://localhost:3000"},
+			Timeout:       5 * time.Minute,
+		},
+		nil,
+		ldap{
+			URL:            "ldap://localhost:389",
+			UserFilter:     "(&(objectClass=person)(mail=%s))",
+			NameAttribute:  "displayName",
+			EmailAttribute: "mail",
+			DefaultRole:    "teacher",
+		},
+		nil,
+		cookies{
+			Mode:     sessionModeHeader,
+			Name:     "lavurso_session",
+			Secure:   true,
+			SameSite: "lax",
+		},
+		anonymization{
+			RetentionPeriod: 3 * 365 * 24 * time.Hour,
+		},
+		search{
+			MinLength:           4,
+			SimilarityThreshold: 0.4,
+			MaxResults:          50,
+		},
+		years{
+			RolloverUndoWindow: 7 * 24 * time.Hour,
+		},
 	}
 
 	configData, err := os.ReadFile("config.toml")
@@ -67,6 +251,12 @@ func (cfg *configuration) checkEnvironment() {
 		cfg.Web.Listen = val
 	}
 
+	val, ok = os.LookupEnv("WEB_FRONTEND_URL")
+	if ok {
+		log.Println("INFO using environment variable WEB_FRONTEND_URL")
+		cfg.Web.FrontendURL = val
+	}
+
 	val, ok = os.LookupEnv("DATABASE_HOST")
 	if ok {
 		log.Println("INFO using environment variable DATABASE_HOST")
@@ -100,4 +290,50 @@ func (cfg *configuration) checkEnvironment() {
 		log.Println("INFO using environment variable DATABASE_NAME")
 		cfg.Database.Name = val
 	}
+	val, ok = os.LookupEnv("MAIL_BACKEND")
+	if ok {
+		log.Println("INFO using environment variable MAIL_BACKEND")
+		cfg.Mail.Backend = val
+	}
+
+	val, ok = os.LookupEnv("MAIL_HOST")
+	if ok {
+		log.Println("INFO using environment variable MAIL_HOST")
+		cfg.Mail.Host = val
+	}
+
+	val, ok = os.LookupEnv("MAIL_PORT")
+	if ok {
+		log.Println("INFO using environment variable MAIL_PORT")
+		port, err := strconv.Atoi(val)
+		if err != nil {
+			log.Println("ERROR failed reading environment variable MAIL_PORT, skipping it")
+		} else {
+			cfg.Mail.Port = port
+		}
+	}
+
+	val, ok = os.LookupEnv("MAIL_USER")
+	if ok {
+		log.Println("INFO using environment variable MAIL_USER")
+		cfg.Mail.User = val
+	}
+
+	val, ok = os.LookupEnv("MAIL_PASSWORD")
+	if ok {
+		log.Println("INFO using environment variable MAIL_PASSWORD")
+		cfg.Mail.Password = val
+	}
+
+	val, ok = os.LookupEnv("MAIL_FROM")
+	if ok {
+		log.Println("INFO using environment variable MAIL_FROM")
+		cfg.Mail.From = val
+	}
+
+	val, ok = os.LookupEnv("LDAP_BIND_PASSWORD")
+	if ok {
+		log.Println("INFO using environment variable LDAP_BIND_PASSWORD")
+		cfg.LDAP.BindPassword = val
+	}
 }
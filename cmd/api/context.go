@@ -22,3 +22,38 @@ func (app *application) getUserFromContext(r *http.Request) *data.UserExt {
 
 	return user
 }
+
+// setImpersonatorForContext stores the ID of the admin acting as the session user
+func (app *application) setImpersonatorForContext(impersonatorID int, r *http.Request) *http.Request {
+	ctx := context.WithValue(r.Context(), lavursoContextKey("impersonator"), impersonatorID)
+	return r.WithContext(ctx)
+}
+
+// getImpersonatorFromContext returns nil unless the session is an impersonation
+func (app *application) getImpersonatorFromContext(r *http.Request) *int {
+	impersonatorID, ok := r.Context().Value(lavursoContextKey("impersonator")).(int)
+	if !ok {
+		return nil
+	}
+
+	return &impersonatorID
+}
+
+func (app *application) setIPForContext(ip string, r *http.Request) *http.Request {
+	ctx := context.WithValue(r.Context(), lavursoContextKey("ip"), ip)
+	return r.WithContext(ctx)
+}
+
+func (app *application) setLogForContext(log *data.Log, r *http.Request) *http.Request {
+	ctx := context.WithValue(r.Context(), lavursoContextKey("log"), log)
+	return r.WithContext(ctx)
+}
+
+func (app *application) getLogFromContext(r *http.Request) *data.Log {
+	log, ok := r.Context().Value(lavursoContextKey("log")).(*data.Log)
+	if !ok {
+		return nil
+	}
+
+	return log
+}
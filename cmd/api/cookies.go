@@ -0,0 +1,131 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/annusingmar/lavurso-backend/internal/data"
+)
+
+// session modes, see the cookies section of the config
+const (
+	sessionModeHeader = "header"
+	sessionModeCookie = "cookie"
+	sessionModeBoth   = "both"
+)
+
+const csrfHeader = "X-CSRF-Token"
+
+var (
+	ErrInvalidCSRFToken = errors.New("missing or invalid CSRF token")
+)
+
+func (c cookies) enabled() bool {
+	return c.Mode == sessionModeCookie || c.Mode == sessionModeBoth
+}
+
+func (c cookies) sameSite() http.SameSite {
+	switch c.SameSite {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
+func (c cookies) cookie(name, value, path string, expires time.Time, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   c.Domain,
+		Expires:  expires,
+		Secure:   c.Secure,
+		HttpOnly: httpOnly,
+		SameSite: c.sameSite(),
+	}
+}
+
+func (c cookies) csrfName() string {
+	return c.Name + "_csrf"
+}
+
+func (c cookies) refreshName() string {
+	return c.Name + "_refresh"
+}
+
+// sessionResponse builds the response for a new session, in the cookie modes
+// it also sets the session cookies and a CSRF token, which the client has to send
+// back in the X-CSRF-Token header
+func (app *application) sessionResponse(w http.ResponseWriter, session *data.Session, refreshToken *data.RefreshToken) (envelope, error) {
+	env := envelope{"session": session}
+	if refreshToken != nil {
+		env["refresh_token"] = refreshToken
+	}
+
+	cfg := app.config.Cookies
+	if !cfg.enabled() {
+		return env, nil
+	}
+
+	csrfToken, err := randomString()
+	if err != nil {
+		return nil, err
+	}
+
+	// without a refresh token the cookies only last until the browser is closed,
+	// the session itself still expires on the server
+	var expires time.Time
+	if refreshToken != nil {
+		expires = *refreshToken.Expires
+		http.SetCookie(w, cfg.cookie(cfg.refreshName(), refreshToken.Token.Plaintext, "/authenticate/refresh", expires, true))
+	}
+
+	http.SetCookie(w, cfg.cookie(cfg.Name, session.Token.Plaintext, "/", expires, true))
+	http.SetCookie(w, cfg.cookie(cfg.csrfName(), csrfToken, "/", expires, false))
+
+	env["csrf_token"] = csrfToken
+
+	if cfg.Mode == sessionModeCookie {
+		session.Token = nil
+		delete(env, "refresh_token")
+	}
+
+	return env, nil
+}
+
+func (app *application) clearSessionCookies(w http.ResponseWriter) {
+	cfg := app.config.Cookies
+	if !cfg.enabled() {
+		return
+	}
+
+	for _, cookie := range []*http.Cookie{
+		cfg.cookie(cfg.Name, "", "/", time.Time{}, true),
+		cfg.cookie(cfg.csrfName(), "", "/", time.Time{}, false),
+		cfg.cookie(cfg.refreshName(), "", "/authenticate/refresh", time.Time{}, true),
+	} {
+		cookie.MaxAge = -1
+		http.SetCookie(w, cookie)
+	}
+}
+
+// validCSRF checks the double-submitted CSRF token of a request authenticated with a cookie,
+// requests that don't change anything don't need one
+func (app *application) validCSRF(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	cookie, err := r.Cookie(app.config.Cookies.csrfName())
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(r.Header.Get(csrfHeader))) == 1
+}
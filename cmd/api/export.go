@@ -0,0 +1,103 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/annusingmar/lavurso-backend/internal/data"
+	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/go-chi/chi/v5"
+)
+
+func (app *application) exportUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if userID < 0 || err != nil {
+		app.writeErrorResponse(w, r, http.StatusNotFound, data.ErrNoSuchUser.Error())
+		return
+	}
+
+	app.writeExport(w, r, userID)
+}
+
+func (app *application) exportOwnData(w http.ResponseWriter, r *http.Request) {
+	sessionUser := app.getUserFromContext(r)
+
+	app.writeExport(w, r, sessionUser.ID)
+}
+
+// writeExport responds with all data about the user, as JSON
+// or as a ZIP archive with query param 'format=zip'
+func (app *application) writeExport(w http.ResponseWriter, r *http.Request, userID int) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "zip" {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, "format must be json or zip")
+		return
+	}
+
+	export, err := app.models.Export.ExportUser(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoSuchUser):
+			app.writeErrorResponse(w, r, http.StatusNotFound, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	if log := app.getLogFromContext(r); log != nil {
+		log.Event = helpers.ToPtr(data.LogEventExport)
+	}
+
+	if format != "zip" {
+		err = app.outputJSON(w, http.StatusOK, envelope{"export": export})
+		if err != nil {
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	exportJSON, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="lavurso-export-%d.zip"`, userID))
+	w.WriteHeader(http.StatusOK)
+
+	// the status is already written, so errors can only be logged
+	archive := zip.NewWriter(w)
+
+	files := []struct {
+		name    string
+		content []byte
+	}{
+		{"export.json", exportJSON},
+		{"README.md", data.ExportDocumentation},
+	}
+
+	for _, f := range files {
+		fw, err := archive.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: export.ExportedAt})
+		if err != nil {
+			app.errorLogger.Println(err)
+			return
+		}
+
+		_, err = fw.Write(f.content)
+		if err != nil {
+			app.errorLogger.Println(err)
+			return
+		}
+	}
+
+	err = archive.Close()
+	if err != nil {
+		app.errorLogger.Println(err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/annusingmar/lavurso-backend/internal/data"
 	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/annusingmar/lavurso-backend/internal/policy"
 	"github.com/annusingmar/lavurso-backend/internal/validator"
 	"github.com/go-chi/chi/v5"
 )
@@ -239,7 +240,7 @@ func (app *application) addUsersToGroup(w http.ResponseWriter, r *http.Request)
 	}
 
 	for _, role := range input.Roles {
-		if role != data.RoleAdministrator && role != data.RoleTeacher && role != data.RoleParent && role != data.RoleStudent {
+		if !app.policy.HasRole(role) {
 			app.writeErrorResponse(w, r, http.StatusBadRequest, fmt.Sprintf("no such role: %s", role))
 			return
 		}
@@ -369,13 +370,8 @@ func (app *application) getGroupsForUser(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	switch *sessionUser.Role {
-	case data.RoleAdministrator:
-	default:
-		if sessionUser.ID != userID {
-			app.notAllowed(w, r)
-			return
-		}
+	if !app.authorize(w, r, policy.GroupView, isUser(sessionUser, userID)) {
+		return
 	}
 
 	user, err := app.models.Users.GetUserByID(userID)
@@ -391,7 +387,7 @@ func (app *application) getGroupsForUser(w http.ResponseWriter, r *http.Request)
 
 	var groups []*data.GroupExt
 
-	if *sessionUser.Role == data.RoleAdministrator || *sessionUser.Role == data.RoleTeacher {
+	if app.can(r, policy.ThreadMessageAnyGroup) {
 		groups, err = app.models.Groups.GetAllGroups(false)
 	} else {
 		groups, err = app.models.Groups.GetGroupsByUserID(user.ID)
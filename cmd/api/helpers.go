@@ -5,8 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"strings"
+
+	"github.com/annusingmar/lavurso-backend/internal/clientip"
 )
 
 type envelope map[string]any
@@ -62,6 +65,21 @@ func (app *application) inputJSON(w http.ResponseWriter, r *http.Request, destin
 	return nil
 }
 
+func (w web) newIPResolver() *clientip.Resolver {
+	trustedProxies, err := clientip.ParsePrefixes(w.TrustedProxies)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	return &clientip.Resolver{TrustedProxies: trustedProxies}
+}
+
+// getIP returns the client's IP address resolved by the resolveIP middleware
 func (app *application) getIP(r *http.Request) string {
-	return r.Header.Get("X-Real-IP")
+	ip, ok := r.Context().Value(lavursoContextKey("ip")).(string)
+	if !ok {
+		return app.ipResolver.ClientIP(r)
+	}
+
+	return ip
 }
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/annusingmar/lavurso-backend/internal/data"
+	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/annusingmar/lavurso-backend/internal/policy"
+	"github.com/go-chi/chi/v5"
+)
+
+var (
+	ErrCannotImpersonate = errors.New("user can't be impersonated")
+)
+
+// impersonateUser starts a short session as the user for the admin,
+// which is marked with the admin's ID so that everything done with it is audited
+func (app *application) impersonateUser(w http.ResponseWriter, r *http.Request) {
+	sessionUser := app.getUserFromContext(r)
+
+	if app.getImpersonatorFromContext(r) != nil {
+		app.notAllowed(w, r)
+		return
+	}
+
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if userID < 0 || err != nil {
+		app.writeErrorResponse(w, r, http.StatusNotFound, data.ErrNoSuchUser.Error())
+		return
+	}
+
+	user, err := app.models.Users.GetUserByID(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoSuchUser):
+			app.writeErrorResponse(w, r, http.StatusNotFound, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	// users who can impersonate others can't be impersonated themselves,
+	// so impersonation never gives more access than the admin already has
+	if user.ID == sessionUser.ID || *user.Archived || !*user.Active ||
+		app.policy.Check(*user.Roles, policy.UserImpersonate) != policy.Denied {
+		app.writeErrorResponse(w, r, http.StatusConflict, ErrCannotImpersonate.Error())
+		return
+	}
+
+	session := app.newSession(r, user.ID, nil)
+	session.ImpersonatorID = &sessionUser.ID
+
+	expires := session.LoggedIn.Add(app.config.Sessions.ImpersonationLifetime)
+	if expires.Before(*session.Expires) {
+		session.Expires = &expires
+	}
+
+	err = session.Token.NewToken()
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.models.Sessions.InsertSession(session)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	if log := app.getLogFromContext(r); log != nil {
+		log.Event = helpers.ToPtr(data.LogEventImpersonate)
+	}
+
+	err = app.outputJSON(w, http.StatusCreated, envelope{"session": session})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
+
+func (app *application) listOwnImpersonations(w http.ResponseWriter, r *http.Request) {
+	sessionUser := app.getUserFromContext(r)
+
+	sessions, err := app.models.Sessions.GetImpersonationsByUserID(sessionUser.ID)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.outputJSON(w, http.StatusOK, envelope{"impersonations": sessions})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/annusingmar/lavurso-backend/internal/data"
+	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/annusingmar/lavurso-backend/internal/isikukood"
+	"github.com/annusingmar/lavurso-backend/internal/types"
+	"github.com/annusingmar/lavurso-backend/internal/validator"
+)
+
+const maxImportSize = 10 << 20
+
+// importColumns are the CSV columns, the header decides their order
+var importColumns = map[string]bool{
+	"name":    true,
+	"email":   true,
+	"id_code": true,
+	"phone":   true,
+	"role":    true,
+	"class":   true,
+	"parent":  true,
+}
+
+var requiredImportColumns = []string{"name", "email", "role"}
+
+type importRow struct {
+	Row     int                 `json:"row"`
+	User    *data.User          `json:"user"`
+	Parents []string            `json:"parents,omitempty"`
+	Errors  map[string][]string `json:"errors,omitempty"`
+}
+
+// importUsers creates users from CSV, where students can be linked to their parents
+// by listing the parents' emails separated by semicolons in the parent column.
+// Parents can be in the same file or already exist. With dry_run=true nothing is
+// written and the planned users with each row's errors are returned.
+func (app *application) importUsers(w http.ResponseWriter, r *http.Request) {
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+
+	reader := csv.NewReader(http.MaxBytesReader(w, r.Body, maxImportSize))
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, "CSV header must be provided")
+		return
+	}
+
+	columns := make(map[string]int)
+	for i, h := range header {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if !importColumns[h] {
+			app.writeErrorResponse(w, r, http.StatusBadRequest, fmt.Sprintf("unknown column %q", h))
+			return
+		}
+		columns[h] = i
+	}
+
+	for _, c := range requiredImportColumns {
+		if _, ok := columns[c]; !ok {
+			app.writeErrorResponse(w, r, http.StatusBadRequest, fmt.Sprintf("column %q must be provided", c))
+			return
+		}
+	}
+
+	var records [][]string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			app.writeErrorResponse(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		records = append(records, record)
+	}
+
+	if len(records) == 0 {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, "CSV must contain at least one user")
+		return
+	}
+
+	field := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	classes, err := app.models.Classes.AllClasses(false)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	// classes can be referred to by their name or this year's display name
+	classIDs := make(map[string]int)
+	for _, c := range classes {
+		classIDs[strings.ToLower(*c.Name)] = c.ID
+		if c.DisplayName != nil {
+			classIDs[strings.ToLower(*c.DisplayName)] = c.ID
+		}
+	}
+
+	var emails []string
+	var idCodes []int64
+	for _, record := range records {
+		emails = append(emails, field(record, "email"))
+		emails = append(emails, splitImportParents(field(record, "parent"))...)
+		if code, err := strconv.ParseInt(field(record, "id_code"), 10, 64); err == nil {
+			idCodes = append(idCodes, code)
+		}
+	}
+
+	existing, err := app.models.Users.GetUsersByEmails(emails)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	// emails are case insensitive, so users are looked up by the lowercase email
+	existingByEmail := make(map[string]*data.User)
+	for _, u := range existing {
+		existingByEmail[strings.ToLower(*u.Email)] = u
+	}
+
+	existingIDCodes := make(map[int64]bool)
+	existing, err = app.models.Users.GetUsersByIDCodes(idCodes)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+	for _, u := range existing {
+		existingIDCodes[*u.IDCode] = true
+	}
+
+	rows := make([]*importRow, len(records))
+	importedByEmail := make(map[string]*data.User)
+	importedIDCodes := make(map[int64]bool)
+
+	for i, record := range records {
+		v := validator.NewValidator()
+
+		user := &data.User{
+			Name:           helpers.ToPtr(field(record, "name")),
+			Email:          helpers.ToPtr(field(record, "email")),
+			Role:           helpers.ToPtr(field(record, "role")),
+			BirthDate:      new(types.Date),
+			TotpEnabled:    helpers.ToPtr(false),
+			ExternalAuth:   helpers.ToPtr(false),
+			ServiceAccount: helpers.ToPtr(false),
+			Password:       new(types.Password),
+		}
+
+		// imported users are invited to choose their password, and hashing
+		// hundreds of random passwords would make the import very slow
+		user.Password.Disable()
+
+		v.Check(*user.Name != "", "name", "must be provided")
+		v.Check(*user.Email != "", "email", "must be provided")
+		v.Check(data.EmailRegex.MatchString(*user.Email), "email", "must be a valid email address")
+		email := strings.ToLower(*user.Email)
+		v.Check(existingByEmail[email] == nil, "email", data.ErrEmailAlreadyExists.Error())
+		v.Check(importedByEmail[email] == nil, "email", "must be unique in the file")
+
+		if idCode := field(record, "id_code"); idCode != "" {
+			code, err := strconv.ParseInt(idCode, 10, 64)
+			if err != nil {
+				v.Add("id_code", isikukood.ErrInvalidLength.Error())
+			} else {
+				user.BirthDate = checkIDCode(v, &code, user.BirthDate)
+				v.Check(!existingIDCodes[code], "id_code", data.ErrIDCodeAlreadyExists.Error())
+				v.Check(!importedIDCodes[code], "id_code", "must be unique in the file")
+				user.IDCode = &code
+				importedIDCodes[code] = true
+			}
+		}
+
+		if phone := field(record, "phone"); phone != "" {
+			user.PhoneNumber = &phone
+		}
+
+		v.Check(app.policy.HasRole(*user.Role), "role", "must be valid role")
+		user.Roles = &types.Roles{*user.Role}
+
+		if *user.Role == data.RoleStudent {
+			class := field(record, "class")
+			classID, ok := classIDs[strings.ToLower(class)]
+			v.Check(class != "", "class", "must be provided")
+			v.Check(class == "" || ok, "class", data.ErrNoSuchClass.Error())
+			if ok {
+				user.ClassID = &classID
+			}
+		}
+
+		rows[i] = &importRow{
+			// the header is the first line
+			Row:     i + 2,
+			User:    user,
+			Parents: splitImportParents(field(record, "parent")),
+			Errors:  v.Errors,
+		}
+
+		if email != "" && importedByEmail[email] == nil {
+			importedByEmail[email] = user
+		}
+	}
+
+	// parents are resolved after all rows are read, so they can be listed after their children
+	var links []data.ParentLink
+	for _, row := range rows {
+		v := &validator.Validator{Errors: row.Errors}
+
+		if len(row.Parents) > 0 {
+			v.Check(*row.User.Role == data.RoleStudent, "parent", data.ErrNotAStudent.Error())
+		}
+
+		for _, email := range row.Parents {
+			parent := importedByEmail[strings.ToLower(email)]
+			if parent == nil {
+				parent = existingByEmail[strings.ToLower(email)]
+			}
+
+			switch {
+			case parent == nil:
+				v.Add("parent", fmt.Sprintf("%s: %s", email, data.ErrNoSuchUser.Error()))
+			case !parent.Roles.Has(data.RoleParent):
+				v.Add("parent", fmt.Sprintf("%s: %s", email, data.ErrNotAParent.Error()))
+			default:
+				links = append(links, data.ParentLink{Parent: parent, Child: row.User})
+			}
+		}
+	}
+
+	var invalid []*importRow
+	for _, row := range rows {
+		if len(row.Errors) > 0 {
+			invalid = append(invalid, row)
+		}
+	}
+
+	if dryRun {
+		err = app.outputJSON(w, http.StatusOK, envelope{"valid": len(invalid) == 0, "rows": rows})
+		if err != nil {
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	if len(invalid) > 0 {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, invalid)
+		return
+	}
+
+	users := make([]*data.User, len(rows))
+	invitations := make([]*data.Invitation, len(rows))
+	for i, row := range rows {
+		users[i] = row.User
+		invitations[i], err = app.newInvitation(r)
+		if err != nil {
+			app.writeInternalServerError(w, r, err)
+			return
+		}
+	}
+
+	err = app.models.Users.ImportUsers(users, invitations, links)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEmailAlreadyExists) || errors.Is(err, data.ErrIDCodeAlreadyExists):
+			app.writeErrorResponse(w, r, http.StatusConflict, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	for i, u := range users {
+		app.sendInvitationMail(u, invitations[i])
+	}
+
+	err = app.outputJSON(w, http.StatusCreated, envelope{"message": "success", "created": len(users)})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
+
+func splitImportParents(value string) []string {
+	var parents []string
+	for _, p := range strings.Split(value, ";") {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			parents = append(parents, p)
+		}
+	}
+	return parents
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/annusingmar/lavurso-backend/internal/data"
+	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/annusingmar/lavurso-backend/internal/mailer"
+	"github.com/annusingmar/lavurso-backend/internal/types"
+	"github.com/annusingmar/lavurso-backend/internal/validator"
+	"github.com/go-chi/chi/v5"
+)
+
+// newInvitation creates an invitation for a new user, it's saved together with the user
+// and emailed to them afterwards
+func (app *application) newInvitation(r *http.Request) (*data.Invitation, error) {
+	sessionUser := app.getUserFromContext(r)
+
+	invitation := &data.Invitation{
+		Token:     new(types.Token),
+		CreatedBy: &sessionUser.ID,
+		Expires:   helpers.ToPtr(time.Now().UTC().Add(app.config.Passwords.InvitationLifetime)),
+	}
+
+	err := invitation.Token.NewToken()
+	if err != nil {
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+func (app *application) sendInvitationMail(user *data.User, invitation *data.Invitation) {
+	link := fmt.Sprintf("%s/invitation?token=%s", app.config.Web.FrontendURL, url.QueryEscape(invitation.Token.Plaintext))
+
+	app.sendMail(&mailer.Message{
+		To:      *user.Email,
+		Subject: "Your Lavurso account",
+		Body: fmt.Sprintf("Hello, %s!\n\nAn account has been created for you in Lavurso. "+
+			"To choose a password and start using it, open the following link:\n\n%s\n\n"+
+			"The link is valid until %s.\n",
+			*user.Name, link, invitation.Expires.Format(time.RFC1123)),
+	})
+}
+
+func (app *application) listInvitations(w http.ResponseWriter, r *http.Request) {
+	invitations, err := app.models.Invitations.GetPendingInvitations()
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.outputJSON(w, http.StatusOK, envelope{"invitations": invitations})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
+
+// resendInvitation sends a pending invitation again with a new link,
+// the old link stops working
+func (app *application) resendInvitation(w http.ResponseWriter, r *http.Request) {
+	invitationID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if invitationID < 0 || err != nil {
+		app.writeErrorResponse(w, r, http.StatusNotFound, data.ErrNoSuchInvitation.Error())
+		return
+	}
+
+	invitation, err := app.models.Invitations.GetInvitationByID(invitationID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoSuchInvitation):
+			app.writeErrorResponse(w, r, http.StatusNotFound, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.models.Users.GetUserByID(*invitation.UserID)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	invitation.Token = new(types.Token)
+	invitation.Expires = helpers.ToPtr(time.Now().UTC().Add(app.config.Passwords.InvitationLifetime))
+
+	err = invitation.Token.NewToken()
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.models.Invitations.RenewInvitation(invitation)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInvitationNotPending):
+			app.writeErrorResponse(w, r, http.StatusConflict, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.sendInvitationMail(&user.User, invitation)
+
+	err = app.outputJSON(w, http.StatusOK, envelope{"message": "success"})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
+
+func (app *application) revokeInvitation(w http.ResponseWriter, r *http.Request) {
+	invitationID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if invitationID < 0 || err != nil {
+		app.writeErrorResponse(w, r, http.StatusNotFound, data.ErrNoSuchInvitation.Error())
+		return
+	}
+
+	invitation, err := app.models.Invitations.GetInvitationByID(invitationID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoSuchInvitation):
+			app.writeErrorResponse(w, r, http.StatusNotFound, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Invitations.RevokeInvitation(invitation.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInvitationNotPending):
+			app.writeErrorResponse(w, r, http.StatusConflict, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	err = app.outputJSON(w, http.StatusOK, envelope{"message": "success"})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
+
+// acceptInvitation lets an invited user set their password and logs them in.
+// With enable_2fa, a TOTP secret is also generated, which is confirmed
+// like any other with POST /me/2fa/finish.
+func (app *application) acceptInvitation(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Token     string `json:"token"`
+		Password  string `json:"password"`
+		Enable2FA bool   `json:"enable_2fa"`
+	}
+
+	err := app.inputJSON(w, r, &input)
+	if err != nil {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	v := validator.NewValidator()
+
+	v.Check(input.Token != "", "token", "must be provided")
+	v.Check(input.Password != "", "password", "must be provided")
+
+	if !v.Valid() {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	err = app.validatePassword(v, "password", input.Password, nil)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	invitation, err := app.models.Invitations.GetInvitationByToken(input.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInvalidInvitation):
+			app.writeErrorResponse(w, r, http.StatusBadRequest, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.models.Users.GetUserByID(*invitation.UserID)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	if !*user.Active || *user.Archived {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, data.ErrInvalidInvitation.Error())
+		return
+	}
+
+	err = app.models.Invitations.AcceptInvitation(invitation.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInvalidInvitation):
+			app.writeErrorResponse(w, r, http.StatusBadRequest, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	user.Password.Plaintext = input.Password
+	err = user.Password.CreateHash()
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.models.Users.UpdatePassword(user.ID, user.Password)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	response, err := app.createSession(w, r, user, false)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	if input.Enable2FA && !*user.TotpEnabled {
+		secret, err := app.models.Users.AddTOTPTokenToUser(user.ID)
+		if err != nil {
+			app.writeInternalServerError(w, r, err)
+			return
+		}
+
+		response["2fa"] = envelope{"uri": secret.URI(app.config.TOTP.Issuer, *user.Email), "secret": secret}
+	}
+
+	err = app.outputJSON(w, http.StatusAccepted, response)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
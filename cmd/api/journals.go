@@ -8,6 +8,7 @@ import (
 
 	"github.com/annusingmar/lavurso-backend/internal/data"
 	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/annusingmar/lavurso-backend/internal/policy"
 	"github.com/annusingmar/lavurso-backend/internal/validator"
 	"github.com/go-chi/chi/v5"
 	"golang.org/x/exp/slices"
@@ -51,8 +52,7 @@ func (app *application) getJournal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !journal.IsUserTeacherOfJournal(sessionUser.ID) && *sessionUser.Role != data.RoleAdministrator {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.JournalView, isTeacherOfJournal(sessionUser, journal)) {
 		return
 	}
 
@@ -146,8 +146,7 @@ func (app *application) updateJournal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !journal.IsUserTeacherOfJournal(sessionUser.ID) && *sessionUser.Role != data.RoleAdministrator {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.JournalEdit, isTeacherOfJournal(sessionUser, journal)) {
 		return
 	}
 
@@ -187,7 +186,8 @@ func (app *application) updateJournal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !slices.Contains(input.TeacherIDs, sessionUser.ID) && *sessionUser.Role != data.RoleAdministrator {
+	// users who can only edit their own journals can't remove themselves from it
+	if !slices.Contains(input.TeacherIDs, sessionUser.ID) && !app.can(r, policy.JournalEdit) {
 		input.TeacherIDs = append(input.TeacherIDs, sessionUser.ID)
 	}
 
@@ -248,8 +248,7 @@ func (app *application) getJournalsForTeacher(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	if teacherID != sessionUser.ID && *sessionUser.Role != data.RoleAdministrator {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.TeacherView, isUser(sessionUser, teacherID)) {
 		return
 	}
 
@@ -264,7 +263,7 @@ func (app *application) getJournalsForTeacher(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	if *teacher.Role != data.RoleAdministrator && *teacher.Role != data.RoleTeacher {
+	if app.policy.Check(*teacher.Roles, policy.JournalEdit) == policy.Denied {
 		app.writeErrorResponse(w, r, http.StatusBadRequest, "user not an admin")
 		return
 	}
@@ -301,8 +300,7 @@ func (app *application) addStudentsToJournal(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if !journal.IsUserTeacherOfJournal(sessionUser.ID) && *sessionUser.Role != data.RoleAdministrator {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.JournalEdit, isTeacherOfJournal(sessionUser, journal)) {
 		return
 	}
 
@@ -394,8 +392,7 @@ func (app *application) removeStudentFromJournal(w http.ResponseWriter, r *http.
 		return
 	}
 
-	if !journal.IsUserTeacherOfJournal(sessionUser.ID) && *sessionUser.Role != data.RoleAdministrator {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.JournalEdit, isTeacherOfJournal(sessionUser, journal)) {
 		return
 	}
 
@@ -469,8 +466,7 @@ func (app *application) getStudentsForJournal(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	if !journal.IsUserTeacherOfJournal(sessionUser.ID) && *sessionUser.Role != data.RoleAdministrator {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.JournalView, isTeacherOfJournal(sessionUser, journal)) {
 		return
 	}
 
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/annusingmar/lavurso-backend/internal/data"
+	"github.com/annusingmar/lavurso-backend/internal/directory"
+	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/annusingmar/lavurso-backend/internal/types"
+)
+
+func (config ldap) newDirectory() *directory.Directory {
+	if !config.Enabled {
+		return nil
+	}
+
+	return &directory.Directory{
+		Config: directory.Config{
+			URL:                config.URL,
+			StartTLS:           config.StartTLS,
+			InsecureSkipVerify: config.InsecureSkipVerify,
+			BindDN:             config.BindDN,
+			BindPassword:       config.BindPassword,
+			BaseDN:             config.BaseDN,
+			UserFilter:         config.UserFilter,
+			SyncFilter:         config.SyncFilter,
+			NameAttribute:      config.NameAttribute,
+			EmailAttribute:     config.EmailAttribute,
+			RoleAttribute:      config.RoleAttribute,
+			Roles:              config.Roles,
+			DefaultRole:        config.DefaultRole,
+		},
+	}
+}
+
+// validateExternalPassword checks the password of a user with external_auth against the directory
+func (app *application) validateExternalPassword(user *data.UserExt, password string) (bool, error) {
+	if app.directory == nil {
+		return false, nil
+	}
+
+	_, err := app.directory.Authenticate(*user.Email, password)
+	if err != nil {
+		switch {
+		case errors.Is(err, directory.ErrInvalidCredentials) || errors.Is(err, directory.ErrNoSuchEntry):
+			return false, nil
+		default:
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// syncLDAP creates users found with the sync filter and updates the names of existing ones.
+// Roles of existing users are left alone.
+func (app *application) syncLDAP() error {
+	if app.directory == nil {
+		return errors.New("ldap is not enabled")
+	}
+
+	entries, err := app.directory.SyncEntries()
+	if err != nil {
+		return err
+	}
+
+	var created, updated, skipped int
+
+	for _, entry := range entries {
+		if entry.Email == "" || entry.Name == "" || !data.EmailRegex.MatchString(entry.Email) {
+			app.infoLogger.Printf("ldap-sync: skipping %s, name or email missing", entry.DN)
+			skipped++
+			continue
+		}
+
+		user, err := app.models.Users.GetUserByEmail(entry.Email)
+		if err != nil && !errors.Is(err, data.ErrNoSuchUser) {
+			return err
+		}
+
+		if user != nil {
+			user.Name = &entry.Name
+			user.ExternalAuth = helpers.ToPtr(true)
+
+			err = app.models.Users.UpdateUser(user)
+			if err != nil {
+				return err
+			}
+
+			updated++
+			continue
+		}
+
+		if !app.policy.HasRole(entry.Role) || entry.Role == data.RoleStudent {
+			app.infoLogger.Printf("ldap-sync: skipping %s, role %q can't be synced", entry.DN, entry.Role)
+			skipped++
+			continue
+		}
+
+		// the password is never used, but is required
+		password, err := randomString()
+		if err != nil {
+			return err
+		}
+
+		newUser := &data.User{
+			Name:           &entry.Name,
+			Email:          &entry.Email,
+			Password:       &types.Password{Plaintext: password},
+			BirthDate:      new(types.Date),
+			Role:           &entry.Role,
+			Roles:          &types.Roles{entry.Role},
+			TotpEnabled:    helpers.ToPtr(false),
+			ExternalAuth:   helpers.ToPtr(true),
+			ServiceAccount: helpers.ToPtr(false),
+		}
+
+		err = newUser.Password.CreateHash()
+		if err != nil {
+			return err
+		}
+
+		err = app.models.Users.InsertUser(newUser, nil)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrEmailAlreadyExists):
+				app.infoLogger.Printf("ldap-sync: skipping %s, email belongs to an archived or inactive user", entry.DN)
+				skipped++
+				continue
+			default:
+				return err
+			}
+		}
+
+		created++
+	}
+
+	app.infoLogger.Printf("ldap-sync: %d users created, %d updated, %d skipped", created, updated, skipped)
+
+	return nil
+}
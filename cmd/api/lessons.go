@@ -8,6 +8,7 @@ import (
 
 	"github.com/annusingmar/lavurso-backend/internal/data"
 	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/annusingmar/lavurso-backend/internal/policy"
 	"github.com/annusingmar/lavurso-backend/internal/types"
 	"github.com/annusingmar/lavurso-backend/internal/validator"
 	"github.com/go-chi/chi/v5"
@@ -67,8 +68,7 @@ func (app *application) createLesson(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !journal.IsUserTeacherOfJournal(sessionUser.ID) && *sessionUser.Role != data.RoleAdministrator {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.JournalEdit, isTeacherOfJournal(sessionUser, journal)) {
 		return
 	}
 
@@ -122,8 +122,7 @@ func (app *application) getLesson(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !journal.IsUserTeacherOfJournal(sessionUser.ID) && *sessionUser.Role != data.RoleAdministrator {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.JournalView, isTeacherOfJournal(sessionUser, journal)) {
 		return
 	}
 
@@ -165,8 +164,7 @@ func (app *application) updateLesson(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !journal.IsUserTeacherOfJournal(sessionUser.ID) && *sessionUser.Role != data.RoleAdministrator {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.JournalEdit, isTeacherOfJournal(sessionUser, journal)) {
 		return
 	}
 
@@ -239,8 +237,7 @@ func (app *application) deleteLesson(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !journal.IsUserTeacherOfJournal(sessionUser.ID) && *sessionUser.Role != data.RoleAdministrator {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.JournalEdit, isTeacherOfJournal(sessionUser, journal)) {
 		return
 	}
 
@@ -281,8 +278,7 @@ func (app *application) getLessonsForJournal(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if !journal.IsUserTeacherOfJournal(sessionUser.ID) && *sessionUser.Role != data.RoleAdministrator {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.JournalView, isTeacherOfJournal(sessionUser, journal)) {
 		return
 	}
 
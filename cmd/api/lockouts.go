@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/annusingmar/lavurso-backend/internal/data"
+	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/go-chi/chi/v5"
+)
+
+func (app *application) writeTooManyAttempts(w http.ResponseWriter, r *http.Request, wait time.Duration, err error) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	app.writeErrorResponse(w, r, http.StatusTooManyRequests, err.Error())
+}
+
+// loginBackoff returns how long has to be waited after the last
+// failed attempt when the account already has the given amount of failures
+func (app *application) loginBackoff(failures int) time.Duration {
+	cfg := app.config.LoginProtection
+
+	if cfg.BackoffBase <= 0 || failures < cfg.FreeAttempts {
+		return 0
+	}
+
+	delay := cfg.BackoffBase
+	for i := cfg.FreeAttempts; i < failures && delay < cfg.BackoffMax; i++ {
+		delay *= 2
+	}
+
+	if cfg.BackoffMax > 0 && delay > cfg.BackoffMax {
+		delay = cfg.BackoffMax
+	}
+
+	return delay
+}
+
+// checkLoginAllowed writes an error response and returns false
+// if the user or IP address is locked out or has to wait before trying again
+func (app *application) checkLoginAllowed(w http.ResponseWriter, r *http.Request, userID *int, ip string) bool {
+	lockout, err := app.models.Lockouts.GetActiveLockout(userID, ip)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return false
+	}
+
+	if lockout != nil {
+		app.writeTooManyAttempts(w, r, time.Until(*lockout.LockedUntil), data.ErrTemporarilyLocked)
+		return false
+	}
+
+	if userID == nil {
+		return true
+	}
+
+	stats, err := app.models.Lockouts.GetFailedLoginStatsForUser(*userID, time.Now().UTC().Add(-app.config.LoginProtection.Window))
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return false
+	}
+
+	if stats.Last != nil {
+		wait := time.Until(stats.Last.Add(app.loginBackoff(stats.Count)))
+		if wait > 0 {
+			app.writeTooManyAttempts(w, r, wait, data.ErrTooManyAttempts)
+			return false
+		}
+	}
+
+	return true
+}
+
+// registerFailedLogin records a failed attempt and locks out
+// the user or IP address if they have reached the limit
+func (app *application) registerFailedLogin(userID *int, ip string, r *http.Request) error {
+	cfg := app.config.LoginProtection
+
+	err := app.models.Lockouts.InsertFailedLogin(&data.FailedLogin{
+		UserID: userID,
+		IP:     &ip,
+	})
+	if err != nil {
+		return err
+	}
+
+	log := app.getLogFromContext(r)
+	if log != nil {
+		log.UserID = userID
+		log.Event = helpers.ToPtr(data.LogEventFailedLogin)
+	}
+
+	since := time.Now().UTC().Add(-cfg.Window)
+	lockedUntil := time.Now().UTC().Add(cfg.LockoutDuration)
+
+	if userID != nil && cfg.AccountMaxAttempts > 0 {
+		stats, err := app.models.Lockouts.GetFailedLoginStatsForUser(*userID, since)
+		if err != nil {
+			return err
+		}
+
+		if stats.Count >= cfg.AccountMaxAttempts {
+			err = app.models.Lockouts.InsertLockout(&data.Lockout{UserID: userID, LockedUntil: &lockedUntil})
+			if err != nil {
+				return err
+			}
+
+			err = app.models.Lockouts.DeleteFailedLoginsForUser(*userID)
+			if err != nil {
+				return err
+			}
+
+			if log != nil {
+				log.Event = helpers.ToPtr(data.LogEventLockout)
+			}
+		}
+	}
+
+	if cfg.IPMaxAttempts > 0 {
+		stats, err := app.models.Lockouts.GetFailedLoginStatsForIP(ip, since)
+		if err != nil {
+			return err
+		}
+
+		if stats.Count >= cfg.IPMaxAttempts {
+			err = app.models.Lockouts.InsertLockout(&data.Lockout{IP: &ip, LockedUntil: &lockedUntil})
+			if err != nil {
+				return err
+			}
+
+			err = app.models.Lockouts.DeleteFailedLoginsForIP(ip)
+			if err != nil {
+				return err
+			}
+
+			if log != nil {
+				log.Event = helpers.ToPtr(data.LogEventLockout)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (app *application) listLockouts(w http.ResponseWriter, r *http.Request) {
+	lockouts, err := app.models.Lockouts.AllActiveLockouts()
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.outputJSON(w, http.StatusOK, envelope{"lockouts": lockouts})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
+
+func (app *application) clearLockout(w http.ResponseWriter, r *http.Request) {
+	sessionUser := app.getUserFromContext(r)
+
+	lockoutID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if lockoutID < 0 || err != nil {
+		app.writeErrorResponse(w, r, http.StatusNotFound, data.ErrNoSuchLockout.Error())
+		return
+	}
+
+	lockout, err := app.models.Lockouts.GetActiveLockoutByID(lockoutID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoSuchLockout):
+			app.writeErrorResponse(w, r, http.StatusNotFound, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Lockouts.ClearLockout(lockout.ID, sessionUser.ID)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	if lockout.UserID != nil {
+		err = app.models.Lockouts.DeleteFailedLoginsForUser(*lockout.UserID)
+		if err != nil {
+			app.writeInternalServerError(w, r, err)
+			return
+		}
+	}
+
+	if lockout.IP != nil {
+		err = app.models.Lockouts.DeleteFailedLoginsForIP(*lockout.IP)
+		if err != nil {
+			app.writeInternalServerError(w, r, err)
+			return
+		}
+	}
+
+	err = app.outputJSON(w, http.StatusOK, envelope{"message": "success"})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/annusingmar/lavurso-backend/internal/mailer"
+)
+
+func (config mail) newMailer() mailer.Mailer {
+	switch config.Backend {
+	case "smtp":
+		return &mailer.SMTPMailer{
+			Host:     config.Host,
+			Port:     config.Port,
+			Username: config.User,
+			Password: config.Password,
+			From:     config.From,
+		}
+	case "log":
+		output := os.Stdout
+		if config.LogFile != "" {
+			f, err := os.OpenFile(config.LogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			output = f
+		}
+		return &mailer.LogMailer{Logger: log.New(output, "MAIL ", log.Ltime|log.Ldate)}
+	default:
+		log.Fatalf("unknown mail backend %q", config.Backend)
+		return nil
+	}
+}
+
+func (app *application) sendMail(msg *mailer.Message) {
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				app.errorLogger.Println(err)
+			}
+		}()
+
+		err := app.mailer.Send(msg)
+		if err != nil {
+			app.errorLogger.Println("sending mail to", msg.To, "failed:", err)
+		}
+	}()
+}
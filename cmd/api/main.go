@@ -7,17 +7,31 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/annusingmar/lavurso-backend/internal/clientip"
 	"github.com/annusingmar/lavurso-backend/internal/data"
+	"github.com/annusingmar/lavurso-backend/internal/directory"
+	"github.com/annusingmar/lavurso-backend/internal/mailer"
+	"github.com/annusingmar/lavurso-backend/internal/policy"
+	"github.com/go-webauthn/webauthn/webauthn"
 )
 
 type application struct {
-	config      configuration
-	infoLogger  *log.Logger
-	errorLogger *log.Logger
-	models      data.Models
+	config          configuration
+	infoLogger      *log.Logger
+	errorLogger     *log.Logger
+	models          data.Models
+	mailer          mailer.Mailer
+	webAuthn        *webauthn.WebAuthn
+	oidcClients     map[string]*oidcClient
+	oidcMutex       sync.Mutex
+	directory       *directory.Directory
+	policy          *policy.Policy
+	bannedPasswords map[string]bool
+	ipResolver      *clientip.Resolver
 }
 
 func main() {
@@ -29,11 +43,38 @@ func main() {
 	db := config.Database.openConnection()
 	models := data.NewModel(db)
 
+	mailer := config.Mail.newMailer()
+
+	webAuthn := config.WebAuthn.newWebAuthn()
+
 	app := &application{
-		config:      config,
-		infoLogger:  infoLogger,
-		errorLogger: errorLogger,
-		models:      models,
+		config:          config,
+		infoLogger:      infoLogger,
+		errorLogger:     errorLogger,
+		models:          models,
+		mailer:          mailer,
+		webAuthn:        webAuthn,
+		oidcClients:     make(map[string]*oidcClient),
+		directory:       config.LDAP.newDirectory(),
+		policy:          newPolicy(config.Roles),
+		bannedPasswords: config.Passwords.loadBannedPasswords(),
+		ipResolver:      config.Web.newIPResolver(),
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "ldap-sync" {
+		err := app.syncLDAP()
+		if err != nil {
+			app.errorLogger.Fatalln(err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "anonymize" {
+		err := app.anonymizeExpiredUsers()
+		if err != nil {
+			app.errorLogger.Fatalln(err)
+		}
+		return
 	}
 
 	server := &http.Server{
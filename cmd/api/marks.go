@@ -10,6 +10,7 @@ import (
 
 	"github.com/annusingmar/lavurso-backend/internal/data"
 	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/annusingmar/lavurso-backend/internal/policy"
 	"github.com/annusingmar/lavurso-backend/internal/validator"
 	"github.com/go-chi/chi/v5"
 	"golang.org/x/exp/maps"
@@ -42,16 +43,8 @@ func (app *application) getMarksForStudent(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if sessionUser.ID != student.ID && *sessionUser.Role != data.RoleAdministrator {
-		ok, err := app.models.Users.IsUserTeacherOrParentOfStudent(student.ID, sessionUser.ID)
-		if err != nil {
-			app.writeInternalServerError(w, r, err)
-			return
-		}
-		if !ok {
-			app.notAllowed(w, r)
-			return
-		}
+	if !app.authorize(w, r, policy.StudentView, app.isRelatedToStudent(sessionUser, student.ID)) {
+		return
 	}
 
 	journals, err := app.models.Journals.GetJournalsByStudent(student.ID, year)
@@ -114,16 +107,8 @@ func (app *application) getGradesByYearForStudent(w http.ResponseWriter, r *http
 		return
 	}
 
-	if sessionUser.ID != student.ID && *sessionUser.Role != data.RoleAdministrator {
-		ok, err := app.models.Users.IsUserTeacherOrParentOfStudent(student.ID, sessionUser.ID)
-		if err != nil {
-			app.writeInternalServerError(w, r, err)
-			return
-		}
-		if !ok {
-			app.notAllowed(w, r)
-			return
-		}
+	if !app.authorize(w, r, policy.StudentView, app.isRelatedToStudent(sessionUser, student.ID)) {
+		return
 	}
 
 	years, err := app.models.Years.GetYearsForStudent(student.ID)
@@ -196,8 +181,7 @@ func (app *application) getMarksForLesson(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if !journal.IsUserTeacherOfJournal(sessionUser.ID) && *sessionUser.Role != data.RoleAdministrator {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.JournalView, isTeacherOfJournal(sessionUser, journal)) {
 		return
 	}
 
@@ -238,8 +222,7 @@ func (app *application) getMarksForCourse(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if !journal.IsUserTeacherOfJournal(sessionUser.ID) && *sessionUser.Role != data.RoleAdministrator {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.JournalView, isTeacherOfJournal(sessionUser, journal)) {
 		return
 	}
 
@@ -274,8 +257,7 @@ func (app *application) getMarksForJournalSubject(w http.ResponseWriter, r *http
 		return
 	}
 
-	if !journal.IsUserTeacherOfJournal(sessionUser.ID) && *sessionUser.Role != data.RoleAdministrator {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.JournalView, isTeacherOfJournal(sessionUser, journal)) {
 		return
 	}
 
@@ -321,8 +303,7 @@ func (app *application) setMarksForLesson(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if !journal.IsUserTeacherOfJournal(sessionUser.ID) && *sessionUser.Role != data.RoleAdministrator {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.JournalEditMarks, isTeacherOfJournal(sessionUser, journal)) {
 		return
 	}
 
@@ -559,8 +540,7 @@ func (app *application) setMarksForCourse(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if !journal.IsUserTeacherOfJournal(sessionUser.ID) && *sessionUser.Role != data.RoleAdministrator {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.JournalEditMarks, isTeacherOfJournal(sessionUser, journal)) {
 		return
 	}
 
@@ -729,8 +709,7 @@ func (app *application) setMarksForJournalSubject(w http.ResponseWriter, r *http
 		return
 	}
 
-	if !journal.IsUserTeacherOfJournal(sessionUser.ID) && *sessionUser.Role != data.RoleAdministrator {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.JournalEditMarks, isTeacherOfJournal(sessionUser, journal)) {
 		return
 	}
 
@@ -898,16 +877,8 @@ func (app *application) getLessonsForStudentsJournalsCourse(w http.ResponseWrite
 		return
 	}
 
-	if sessionUser.ID != student.ID && *sessionUser.Role != data.RoleAdministrator {
-		ok, err := app.models.Users.IsUserTeacherOrParentOfStudent(student.ID, sessionUser.ID)
-		if err != nil {
-			app.writeInternalServerError(w, r, err)
-			return
-		}
-		if !ok {
-			app.notAllowed(w, r)
-			return
-		}
+	if !app.authorize(w, r, policy.StudentView, app.isRelatedToStudent(sessionUser, student.ID)) {
+		return
 	}
 
 	journalID, err := strconv.Atoi(chi.URLParam(r, "jid"))
@@ -9,12 +9,13 @@ import (
 
 	"github.com/annusingmar/lavurso-backend/internal/data"
 	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/annusingmar/lavurso-backend/internal/policy"
 	"github.com/annusingmar/lavurso-backend/internal/validator"
 	"github.com/go-chi/chi/v5"
 	"golang.org/x/exp/slices"
 )
 
-func (app *application) verifyUserAndGroupIDs(userIDs, groupIDs []int, userID int, userRole string) ([]int, error) {
+func (app *application) verifyUserAndGroupIDs(userIDs, groupIDs []int, userID int, anyGroup bool) ([]int, error) {
 	if len(userIDs) > 0 {
 		allUserIDs, err := app.models.Users.GetAllUserIDs()
 		if err != nil {
@@ -31,7 +32,7 @@ func (app *application) verifyUserAndGroupIDs(userIDs, groupIDs []int, userID in
 		var allGroupIDs []int
 		var err error
 
-		if userRole == data.RoleAdministrator || userRole == data.RoleTeacher {
+		if anyGroup {
 			allGroupIDs, err = app.models.Groups.GetAllGroupIDs()
 		} else {
 			allGroupIDs, err = app.models.Groups.GetAllGroupIDsForUser(userID)
@@ -90,7 +91,7 @@ func (app *application) createThread(w http.ResponseWriter, r *http.Request) {
 		input.UserIDs = append(input.UserIDs, sessionUser.ID)
 	}
 
-	badIDs, err := app.verifyUserAndGroupIDs(input.UserIDs, input.GroupIDs, sessionUser.ID, *sessionUser.Role)
+	badIDs, err := app.verifyUserAndGroupIDs(input.UserIDs, input.GroupIDs, sessionUser.ID, app.can(r, policy.ThreadMessageAnyGroup))
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrNoSuchUsers) || errors.Is(err, data.ErrNoSuchGroups):
@@ -166,8 +167,7 @@ func (app *application) deleteThread(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if *thread.UserID != sessionUser.ID {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.ThreadDelete, isCreatorOfThread(sessionUser, thread)) {
 		return
 	}
 
@@ -203,8 +203,7 @@ func (app *application) lockThread(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if *thread.UserID != sessionUser.ID {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.ThreadLock, isCreatorOfThread(sessionUser, thread)) {
 		return
 	}
 
@@ -245,8 +244,7 @@ func (app *application) unlockThread(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if *thread.UserID != sessionUser.ID {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.ThreadLock, isCreatorOfThread(sessionUser, thread)) {
 		return
 	}
 
@@ -287,8 +285,7 @@ func (app *application) addMembersToThread(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if *thread.UserID != sessionUser.ID {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.ThreadManageMembers, isCreatorOfThread(sessionUser, thread)) {
 		return
 	}
 
@@ -303,7 +300,7 @@ func (app *application) addMembersToThread(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	badIDs, err := app.verifyUserAndGroupIDs(input.UserIDs, input.GroupIDs, sessionUser.ID, *sessionUser.Role)
+	badIDs, err := app.verifyUserAndGroupIDs(input.UserIDs, input.GroupIDs, sessionUser.ID, app.can(r, policy.ThreadMessageAnyGroup))
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrNoSuchUsers) || errors.Is(err, data.ErrNoSuchGroups):
@@ -357,8 +354,7 @@ func (app *application) removeMembersFromThread(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	if *thread.UserID != sessionUser.ID {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.ThreadManageMembers, isCreatorOfThread(sessionUser, thread)) {
 		return
 	}
 
@@ -441,13 +437,7 @@ func (app *application) createMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ok, err := app.models.Messaging.IsUserInThread(sessionUser.ID, thread.ID)
-	if err != nil {
-		app.writeInternalServerError(w, r, err)
-		return
-	}
-	if !ok {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.MessageCreate, app.isInThread(sessionUser, thread.ID)) {
 		return
 	}
 
@@ -531,14 +521,7 @@ func (app *application) updateMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ok, err := app.models.Messaging.IsUserInThread(sessionUser.ID, *message.ThreadID)
-	if err != nil {
-		app.writeInternalServerError(w, r, err)
-		return
-	}
-
-	if *message.UserID != sessionUser.ID || !ok {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.MessageEdit, app.isAuthorOfMessage(sessionUser, message)) {
 		return
 	}
 
@@ -598,14 +581,7 @@ func (app *application) deleteMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ok, err := app.models.Messaging.IsUserInThread(sessionUser.ID, *message.ThreadID)
-	if err != nil {
-		app.writeInternalServerError(w, r, err)
-		return
-	}
-
-	if *message.UserID != sessionUser.ID || !ok {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.MessageEdit, app.isAuthorOfMessage(sessionUser, message)) {
 		return
 	}
 
@@ -645,13 +621,7 @@ func (app *application) getThread(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ok, err := app.models.Messaging.IsUserInThread(sessionUser.ID, thread.ID)
-	if err != nil {
-		app.writeInternalServerError(w, r, err)
-		return
-	}
-	if !ok {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.ThreadView, app.isInThread(sessionUser, thread.ID)) {
 		return
 	}
 
@@ -693,13 +663,7 @@ func (app *application) getThreadMembers(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	ok, err := app.models.Messaging.IsUserInThread(sessionUser.ID, thread.ID)
-	if err != nil {
-		app.writeInternalServerError(w, r, err)
-		return
-	}
-	if !ok {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.ThreadView, app.isInThread(sessionUser, thread.ID)) {
 		return
 	}
 
@@ -8,31 +8,73 @@ import (
 
 	"github.com/annusingmar/lavurso-backend/internal/data"
 	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/annusingmar/lavurso-backend/internal/policy"
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
 var (
 	ErrAuthenticationRequired = errors.New("authentication required")
+	ErrImpersonationReadOnly  = errors.New("changes can't be made while impersonating a user")
 )
 
+// resolveIP finds the client's IP address once, so that login protection,
+// sessions and logs all use the same one
+func (app *application) resolveIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = app.setIPForContext(app.ipResolver.ClientIP(r), r)
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (app *application) authenticateSession(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 
-		if authHeader == "" {
+		var token string
+		var fromCookie bool
+
+		switch {
+		case authHeader != "":
+			splitHeader := strings.Split(authHeader, " ")
+			if len(splitHeader) != 2 || splitHeader[0] != "Bearer" {
+				app.writeErrorResponse(w, r, http.StatusUnauthorized, data.ErrInvalidToken.Error())
+				return
+			}
+
+			token = splitHeader[1]
+
+			if strings.HasPrefix(token, data.APITokenPrefix) {
+				app.authenticateAPIToken(w, r, next, token)
+				return
+			}
+		case app.config.Cookies.enabled():
+			cookie, err := r.Cookie(app.config.Cookies.Name)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token = cookie.Value
+			fromCookie = true
+		default:
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		splitHeader := strings.Split(authHeader, " ")
-		if len(splitHeader) != 2 || splitHeader[0] != "Bearer" || len(splitHeader[1]) != 52 {
+		if !fromCookie && len(token) != 52 {
 			app.writeErrorResponse(w, r, http.StatusUnauthorized, data.ErrInvalidToken.Error())
 			return
 		}
 
-		user, err := app.models.Users.GetUserBySessionToken(splitHeader[1])
+		user, err := app.models.Users.GetUserBySessionToken(token)
 		if err != nil {
 			switch {
+			case errors.Is(err, data.ErrInvalidToken) && fromCookie:
+				// browsers keep sending expired session cookies, so they are
+				// cleared and the request continues unauthenticated, e.g. to log in again
+				app.clearSessionCookies(w)
+				next.ServeHTTP(w, r)
 			case errors.Is(err, data.ErrInvalidToken):
 				app.writeErrorResponse(w, r, http.StatusUnauthorized, err.Error())
 			default:
@@ -41,53 +83,125 @@ func (app *application) authenticateSession(next http.Handler) http.Handler {
 			return
 		}
 
-		err = app.models.Sessions.ExtendSession(*user.SessionID)
+		if fromCookie && !app.validCSRF(r) {
+			app.writeErrorResponse(w, r, http.StatusForbidden, ErrInvalidCSRFToken.Error())
+			return
+		}
+
+		absoluteLifetime := app.config.Sessions.AbsoluteLifetime
+		if user.ImpersonatorID != nil {
+			absoluteLifetime = app.config.Sessions.ImpersonationLifetime
+		}
+
+		err = app.models.Sessions.ExtendSession(*user.SessionID, app.config.Sessions.IdleTimeout, absoluteLifetime)
 		if err != nil {
 			app.writeInternalServerError(w, r, err)
 			return
 		}
 
 		r = app.setUserForContext(user, r)
+		if user.ImpersonatorID != nil {
+			r = app.setImpersonatorForContext(*user.ImpersonatorID, r)
+		}
 		next.ServeHTTP(w, r)
 	})
 }
 
-func (app *application) requireAuthenticatedUser(next http.Handler) http.Handler {
+func (app *application) authenticateAPIToken(w http.ResponseWriter, r *http.Request, next http.Handler, token string) {
+	if len(token) != len(data.APITokenPrefix)+52 {
+		app.writeErrorResponse(w, r, http.StatusUnauthorized, data.ErrInvalidToken.Error())
+		return
+	}
+
+	user, err := app.models.Users.GetUserByAPIToken(token)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInvalidToken):
+			app.writeErrorResponse(w, r, http.StatusUnauthorized, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.APITokens.SetAPITokenUsed(user.APIToken.ID)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	r = app.setUserForContext(user, r)
+	next.ServeHTTP(w, r)
+}
+
+// requireAPITokenScope lets requests authenticated with an API token
+// through only if the token has the scope the route requires
+func (app *application) requireAPITokenScope(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user := app.getUserFromContext(r)
-		if user == nil {
-			app.writeErrorResponse(w, r, http.StatusUnauthorized, ErrAuthenticationRequired.Error())
+		if user == nil || user.APIToken == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		scope, ok := routeScope(r.Method, chi.RouteContext(r.Context()).RoutePattern())
+		if !ok || !user.APIToken.Scopes.Has(scope) {
+			app.writeErrorResponse(w, r, http.StatusForbidden, data.ErrInsufficientScope.Error())
 			return
 		}
+
 		next.ServeHTTP(w, r)
 	})
 }
 
-func (app *application) requireAdministrator(next http.Handler) http.Handler {
+// blockImpersonatedWrites makes impersonation sessions read-only,
+// apart from logging out, unless writes are allowed in the config
+func (app *application) blockImpersonatedWrites(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		user := app.getUserFromContext(r)
-		if *user.Role != data.RoleAdministrator {
-			app.notAllowed(w, r)
+		if app.getImpersonatorFromContext(r) == nil || app.config.Sessions.ImpersonationAllowWrites {
+			next.ServeHTTP(w, r)
 			return
 		}
-		next.ServeHTTP(w, r)
+
+		if r.Method == http.MethodGet || r.Method == http.MethodHead ||
+			chi.RouteContext(r.Context()).RoutePattern() == "/me/logout" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		app.writeErrorResponse(w, r, http.StatusForbidden, ErrImpersonationReadOnly.Error())
 	})
 }
 
-func (app *application) requireTeacher(next http.Handler) http.Handler {
+func (app *application) requireAuthenticatedUser(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user := app.getUserFromContext(r)
-		if *user.Role != data.RoleTeacher && *user.Role != data.RoleAdministrator {
-			app.notAllowed(w, r)
+		if user == nil {
+			app.writeErrorResponse(w, r, http.StatusUnauthorized, ErrAuthenticationRequired.Error())
 			return
 		}
 		next.ServeHTTP(w, r)
 	})
 }
 
+// requirePermission lets through users whose role has the permission for all resources,
+// routes where it depends on the resource authorize in the handler instead
+func (app *application) requirePermission(permission policy.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !app.can(r, permission) {
+				app.notAllowed(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func (app *application) log(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.EscapedPath() == "/me/unread" {
+		// polling for unread messages isn't logged, unless the session is impersonated
+		if r.URL.EscapedPath() == "/me/unread" && app.getImpersonatorFromContext(r) == nil {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -105,10 +219,17 @@ func (app *application) log(next http.Handler) http.Handler {
 		if user != nil {
 			log.UserID = &user.ID
 			log.SessionID = user.SessionID
+			if user.APIToken != nil {
+				log.APITokenID = &user.APIToken.ID
+			}
+			log.ImpersonatorID = app.getImpersonatorFromContext(r)
 		}
 
 		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 
+		// handlers can add details to the log entry through the request context
+		r = app.setLogForContext(log, r)
+
 		next.ServeHTTP(ww, r)
 
 		log.ResponseCode = helpers.ToPtr(ww.Status())
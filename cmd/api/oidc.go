@@ -0,0 +1,470 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/annusingmar/lavurso-backend/internal/data"
+	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/annusingmar/lavurso-backend/internal/validator"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrNoSuchOIDCProvider = errors.New("no such login provider")
+	ErrOIDCLoginFailed    = errors.New("login with provider failed")
+)
+
+const oidcStateLifetime = 10 * time.Minute
+
+type oidcClient struct {
+	name         string
+	config       oauth2.Config
+	verifier     *oidc.IDTokenVerifier
+	matchByEmail bool
+}
+
+// getOIDCClient returns the client for the provider, running discovery on first use
+// so a provider being unreachable doesn't stop the server from starting
+func (app *application) getOIDCClient(name string) (*oidcClient, error) {
+	app.oidcMutex.Lock()
+	defer app.oidcMutex.Unlock()
+
+	if client, ok := app.oidcClients[name]; ok {
+		return client, nil
+	}
+
+	for _, p := range app.config.OIDC {
+		if p.Name != name {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		provider, err := oidc.NewProvider(ctx, p.Issuer)
+		if err != nil {
+			return nil, err
+		}
+
+		scopes := p.Scopes
+		if len(scopes) == 0 {
+			scopes = []string{oidc.ScopeOpenID, "email", "profile"}
+		}
+
+		client := &oidcClient{
+			name: p.Name,
+			config: oauth2.Config{
+				ClientID:     p.ClientID,
+				ClientSecret: p.ClientSecret,
+				RedirectURL:  p.RedirectURL,
+				Endpoint:     provider.Endpoint(),
+				Scopes:       scopes,
+			},
+			verifier:     provider.Verifier(&oidc.Config{ClientID: p.ClientID}),
+			matchByEmail: p.MatchByEmail,
+		}
+
+		app.oidcClients[name] = client
+
+		return client, nil
+	}
+
+	return nil, ErrNoSuchOIDCProvider
+}
+
+func (app *application) isOIDCProvider(name string) bool {
+	for _, p := range app.config.OIDC {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (app *application) listOIDCProviders(w http.ResponseWriter, r *http.Request) {
+	type provider struct {
+		Name        string `json:"name"`
+		DisplayName string `json:"display_name"`
+	}
+
+	providers := []provider{}
+	for _, p := range app.config.OIDC {
+		providers = append(providers, provider{Name: p.Name, DisplayName: p.DisplayName})
+	}
+
+	err := app.outputJSON(w, http.StatusOK, envelope{"providers": providers})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
+
+func (app *application) beginOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	client, err := app.getOIDCClient(chi.URLParam(r, "provider"))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNoSuchOIDCProvider):
+			app.writeErrorResponse(w, r, http.StatusNotFound, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	state, err := randomString()
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	nonce, err := randomString()
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	verifier := oauth2.GenerateVerifier()
+
+	err = app.models.ExternalIdentities.InsertOIDCState(&data.OIDCState{
+		State:        &state,
+		Provider:     &client.name,
+		Nonce:        &nonce,
+		CodeVerifier: &verifier,
+		Expires:      helpers.ToPtr(time.Now().UTC().Add(oidcStateLifetime)),
+	})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	url := client.config.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+
+	err = app.outputJSON(w, http.StatusOK, envelope{"url": url})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
+
+func (app *application) authenticateWithOIDC(w http.ResponseWriter, r *http.Request) {
+	client, err := app.getOIDCClient(chi.URLParam(r, "provider"))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNoSuchOIDCProvider):
+			app.writeErrorResponse(w, r, http.StatusNotFound, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	// users with 2FA enabled also give their second factor, as with a password,
+	// the state can only be used once, so without it the login has to be started again
+	var input struct {
+		Code         string          `json:"code"`
+		State        string          `json:"state"`
+		OTP          *int            `json:"otp"`
+		RecoveryCode *string         `json:"recovery_code"`
+		Passkey      json.RawMessage `json:"passkey"`
+		RememberMe   bool            `json:"remember_me"`
+	}
+
+	err = app.inputJSON(w, r, &input)
+	if err != nil {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	v := validator.NewValidator()
+
+	v.Check(input.Code != "", "code", "must be provided")
+	v.Check(input.State != "", "state", "must be provided")
+
+	if !v.Valid() {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	state, err := app.models.ExternalIdentities.UseOIDCState(input.State, client.name)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInvalidOIDCState):
+			app.writeErrorResponse(w, r, http.StatusBadRequest, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	token, err := client.config.Exchange(ctx, input.Code, oauth2.VerifierOption(*state.CodeVerifier))
+	if err != nil {
+		app.writeErrorResponse(w, r, http.StatusForbidden, ErrOIDCLoginFailed.Error())
+		return
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		app.writeErrorResponse(w, r, http.StatusForbidden, ErrOIDCLoginFailed.Error())
+		return
+	}
+
+	idToken, err := client.verifier.Verify(ctx, rawIDToken)
+	if err != nil || idToken.Nonce != *state.Nonce {
+		app.writeErrorResponse(w, r, http.StatusForbidden, ErrOIDCLoginFailed.Error())
+		return
+	}
+
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+	}
+
+	err = idToken.Claims(&claims)
+	if err != nil {
+		app.writeErrorResponse(w, r, http.StatusForbidden, ErrOIDCLoginFailed.Error())
+		return
+	}
+
+	var email *string
+	if claims.Email != "" {
+		email = &claims.Email
+	}
+
+	identity, err := app.models.ExternalIdentities.GetExternalIdentity(client.name, idToken.Subject)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoSuchExternalIdentity):
+			// identities are only linked by email if the provider has verified it,
+			// otherwise anyone could sign up at the provider with someone else's email
+			if !client.matchByEmail || email == nil || !claims.EmailVerified {
+				app.writeErrorResponse(w, r, http.StatusForbidden, data.ErrNoUserForExternalIdentity.Error())
+				return
+			}
+
+			user, err := app.models.Users.GetUserByEmail(*email)
+			if err != nil {
+				switch {
+				case errors.Is(err, data.ErrNoSuchUser):
+					app.writeErrorResponse(w, r, http.StatusForbidden, data.ErrNoUserForExternalIdentity.Error())
+				default:
+					app.writeInternalServerError(w, r, err)
+				}
+				return
+			}
+
+			identity = &data.ExternalIdentity{
+				UserID:   &user.ID,
+				Provider: &client.name,
+				Subject:  &idToken.Subject,
+				Email:    email,
+			}
+
+			err = app.models.ExternalIdentities.InsertExternalIdentity(identity)
+			if err != nil {
+				app.writeInternalServerError(w, r, err)
+				return
+			}
+		default:
+			app.writeInternalServerError(w, r, err)
+			return
+		}
+	}
+
+	user, err := app.models.Users.GetUserByID(*identity.UserID)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	if !*user.Active || *user.Archived || *user.ServiceAccount {
+		app.writeErrorResponse(w, r, http.StatusForbidden, data.ErrNoUserForExternalIdentity.Error())
+		return
+	}
+
+	ip := app.getIP(r)
+
+	if !app.checkLoginAllowed(w, r, &user.ID, ip) {
+		return
+	}
+
+	if !app.checkSecondFactor(w, r, user, ip, input.OTP, input.RecoveryCode, input.Passkey) {
+		return
+	}
+
+	err = app.models.Lockouts.DeleteFailedLoginsForUser(user.ID)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.models.ExternalIdentities.SetExternalIdentityLoggedIn(identity.ID, email)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	response, err := app.createSession(w, r, user, input.RememberMe)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.outputJSON(w, http.StatusAccepted, response)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
+
+func (app *application) getExternalIdentitiesForUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if userID < 0 || err != nil {
+		app.writeErrorResponse(w, r, http.StatusNotFound, data.ErrNoSuchUser.Error())
+		return
+	}
+
+	user, err := app.models.Users.GetUserByID(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoSuchUser):
+			app.writeErrorResponse(w, r, http.StatusNotFound, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	identities, err := app.models.ExternalIdentities.GetExternalIdentitiesForUser(user.ID)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.outputJSON(w, http.StatusOK, envelope{"identities": identities})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
+
+func (app *application) linkExternalIdentity(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if userID < 0 || err != nil {
+		app.writeErrorResponse(w, r, http.StatusNotFound, data.ErrNoSuchUser.Error())
+		return
+	}
+
+	user, err := app.models.Users.GetUserByID(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoSuchUser):
+			app.writeErrorResponse(w, r, http.StatusNotFound, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Provider string `json:"provider"`
+		Subject  string `json:"subject"`
+	}
+
+	err = app.inputJSON(w, r, &input)
+	if err != nil {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	v := validator.NewValidator()
+
+	v.Check(app.isOIDCProvider(input.Provider), "provider", "must be a configured provider")
+	v.Check(input.Subject != "", "subject", "must be provided")
+
+	if !v.Valid() {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	identity := &data.ExternalIdentity{
+		UserID:   &user.ID,
+		Provider: &input.Provider,
+		Subject:  &input.Subject,
+	}
+
+	err = app.models.ExternalIdentities.InsertExternalIdentity(identity)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrExternalIdentityLinked):
+			app.writeErrorResponse(w, r, http.StatusConflict, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	err = app.outputJSON(w, http.StatusCreated, envelope{"message": "success"})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
+
+func (app *application) unlinkExternalIdentity(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "uid"))
+	if userID < 0 || err != nil {
+		app.writeErrorResponse(w, r, http.StatusNotFound, data.ErrNoSuchUser.Error())
+		return
+	}
+
+	identityID, err := strconv.Atoi(chi.URLParam(r, "iid"))
+	if identityID < 0 || err != nil {
+		app.writeErrorResponse(w, r, http.StatusNotFound, data.ErrNoSuchExternalIdentity.Error())
+		return
+	}
+
+	identity, err := app.models.ExternalIdentities.GetExternalIdentityByID(identityID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoSuchExternalIdentity):
+			app.writeErrorResponse(w, r, http.StatusNotFound, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	if *identity.UserID != userID {
+		app.writeErrorResponse(w, r, http.StatusNotFound, data.ErrNoSuchExternalIdentity.Error())
+		return
+	}
+
+	err = app.models.ExternalIdentities.DeleteExternalIdentity(identity.ID)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.outputJSON(w, http.StatusOK, envelope{"message": "success"})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/annusingmar/lavurso-backend/internal/data"
+	"github.com/annusingmar/lavurso-backend/internal/validator"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+func (config webAuthn) newWebAuthn() *webauthn.WebAuthn {
+	timeout := webauthn.TimeoutConfig{
+		Enforce:    true,
+		Timeout:    config.Timeout,
+		TimeoutUVD: config.Timeout,
+	}
+
+	w, err := webauthn.New(&webauthn.Config{
+		RPID:          config.RPID,
+		RPDisplayName: config.RPDisplayName,
+		RPOrigins:     config.RPOrigins,
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        timeout,
+			Registration: timeout,
+		},
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	return w
+}
+
+// validatePasskey checks a login assertion against the challenge issued for it
+// and returns the ID of the user the passkey belongs to
+func (app *application) validatePasskey(assertion json.RawMessage) (int, bool, error) {
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(assertion))
+	if err != nil {
+		return 0, false, nil
+	}
+
+	userID, err := data.ParseWebAuthnUserHandle(parsed.Response.UserHandle)
+	if err != nil {
+		return 0, false, nil
+	}
+
+	session, err := app.models.Passkeys.UsePasskeyChallenge(parsed.Response.CollectedClientData.Challenge, nil)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInvalidPasskey):
+			return 0, false, nil
+		default:
+			return 0, false, err
+		}
+	}
+
+	credentials, err := app.models.Passkeys.GetCredentialsForUser(userID)
+	if err != nil {
+		return 0, false, err
+	}
+
+	user := &data.WebAuthnUser{ID: userID, Credentials: credentials}
+
+	credential, err := app.webAuthn.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+		return user, nil
+	}, *session, parsed)
+	if err != nil || credential.Authenticator.CloneWarning {
+		return 0, false, nil
+	}
+
+	err = app.models.Passkeys.UpdatePasskeyCredential(credential)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return userID, true, nil
+}
+
+func (app *application) beginPasskeyLogin(w http.ResponseWriter, r *http.Request) {
+	assertion, session, err := app.webAuthn.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.models.Passkeys.InsertPasskeyChallenge(session, nil)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.outputJSON(w, http.StatusOK, envelope{"options": assertion})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
+
+func (app *application) authenticateWithPasskey(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Passkey    json.RawMessage `json:"passkey"`
+		RememberMe bool            `json:"remember_me"`
+	}
+
+	err := app.inputJSON(w, r, &input)
+	if err != nil {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	v := validator.NewValidator()
+
+	v.Check(len(input.Passkey) > 0, "passkey", "must be provided")
+
+	if !v.Valid() {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	ip := app.getIP(r)
+
+	if !app.checkLoginAllowed(w, r, nil, ip) {
+		return
+	}
+
+	userID, ok, err := app.validatePasskey(input.Passkey)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	if !ok {
+		err = app.registerFailedLogin(nil, ip, r)
+		if err != nil {
+			app.writeInternalServerError(w, r, err)
+			return
+		}
+		app.writeErrorResponse(w, r, http.StatusForbidden, data.ErrInvalidPasskey.Error())
+		return
+	}
+
+	user, err := app.models.Users.GetUserByID(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoSuchUser):
+			app.writeErrorResponse(w, r, http.StatusForbidden, data.ErrInvalidPasskey.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	if !*user.Active || *user.Archived || *user.ServiceAccount {
+		app.writeErrorResponse(w, r, http.StatusForbidden, data.ErrInvalidPasskey.Error())
+		return
+	}
+
+	if !app.checkLoginAllowed(w, r, &user.ID, ip) {
+		return
+	}
+
+	err = app.models.Lockouts.DeleteFailedLoginsForUser(user.ID)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	response, err := app.createSession(w, r, user, input.RememberMe)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.outputJSON(w, http.StatusAccepted, response)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
+
+func (app *application) listPasskeys(w http.ResponseWriter, r *http.Request) {
+	sessionUser := app.getUserFromContext(r)
+
+	passkeys, err := app.models.Passkeys.GetPasskeysForUser(sessionUser.ID)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.outputJSON(w, http.StatusOK, envelope{"passkeys": passkeys})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
+
+func (app *application) beginPasskeyRegistration(w http.ResponseWriter, r *http.Request) {
+	sessionUser := app.getUserFromContext(r)
+
+	credentials, err := app.models.Passkeys.GetCredentialsForUser(sessionUser.ID)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	user := &data.WebAuthnUser{
+		ID:          sessionUser.ID,
+		Name:        *sessionUser.Email,
+		DisplayName: *sessionUser.Name,
+		Credentials: credentials,
+	}
+
+	var exclusions []protocol.CredentialDescriptor
+	for _, c := range credentials {
+		exclusions = append(exclusions, c.Descriptor())
+	}
+
+	creation, session, err := app.webAuthn.BeginRegistration(user,
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+		webauthn.WithExclusions(exclusions))
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.models.Passkeys.InsertPasskeyChallenge(session, &sessionUser.ID)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.outputJSON(w, http.StatusOK, envelope{"options": creation})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
+
+func (app *application) finishPasskeyRegistration(w http.ResponseWriter, r *http.Request) {
+	sessionUser := app.getUserFromContext(r)
+
+	var input struct {
+		Name    string          `json:"name"`
+		Passkey json.RawMessage `json:"passkey"`
+	}
+
+	err := app.inputJSON(w, r, &input)
+	if err != nil {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	v := validator.NewValidator()
+
+	v.Check(input.Name != "", "name", "must be provided")
+	v.Check(len(input.Passkey) > 0, "passkey", "must be provided")
+
+	if !v.Valid() {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(input.Passkey))
+	if err != nil {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, data.ErrInvalidPasskey.Error())
+		return
+	}
+
+	session, err := app.models.Passkeys.UsePasskeyChallenge(parsed.Response.CollectedClientData.Challenge, &sessionUser.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInvalidPasskey):
+			app.writeErrorResponse(w, r, http.StatusBadRequest, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	credentials, err := app.models.Passkeys.GetCredentialsForUser(sessionUser.ID)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	user := &data.WebAuthnUser{
+		ID:          sessionUser.ID,
+		Name:        *sessionUser.Email,
+		DisplayName: *sessionUser.Name,
+		Credentials: credentials,
+	}
+
+	credential, err := app.webAuthn.CreateCredential(user, *session, parsed)
+	if err != nil {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, data.ErrInvalidPasskey.Error())
+		return
+	}
+
+	for _, c := range credentials {
+		if bytes.Equal(c.ID, credential.ID) {
+			app.writeErrorResponse(w, r, http.StatusConflict, data.ErrPasskeyExists.Error())
+			return
+		}
+	}
+
+	err = app.models.Passkeys.InsertPasskey(sessionUser.ID, input.Name, credential)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.outputJSON(w, http.StatusCreated, envelope{"message": "success"})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
+
+func (app *application) deletePasskey(w http.ResponseWriter, r *http.Request) {
+	sessionUser := app.getUserFromContext(r)
+
+	passkeyID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if passkeyID < 0 || err != nil {
+		app.writeErrorResponse(w, r, http.StatusNotFound, data.ErrNoSuchPasskey.Error())
+		return
+	}
+
+	passkey, err := app.models.Passkeys.GetPasskeyByID(passkeyID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoSuchPasskey):
+			app.writeErrorResponse(w, r, http.StatusNotFound, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	if *passkey.UserID != sessionUser.ID {
+		app.notAllowed(w, r)
+		return
+	}
+
+	err = app.models.Passkeys.DeletePasskey(passkey.ID)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.outputJSON(w, http.StatusOK, envelope{"message": "success"})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
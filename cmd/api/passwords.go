@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/annusingmar/lavurso-backend/internal/data"
+	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/annusingmar/lavurso-backend/internal/mailer"
+	"github.com/annusingmar/lavurso-backend/internal/types"
+	"github.com/annusingmar/lavurso-backend/internal/validator"
+)
+
+// loadBannedPasswords reads the banned passwords file, with one password per line
+func (p passwords) loadBannedPasswords() map[string]bool {
+	banned := make(map[string]bool)
+
+	if p.BannedPasswordsFile == "" {
+		return banned
+	}
+
+	file, err := os.Open(p.BannedPasswordsFile)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" {
+			banned[strings.ToLower(line)] = true
+		}
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	return banned
+}
+
+// validatePassword checks a new password against the password policy,
+// for existing users it also can't be one of their recent passwords
+func (app *application) validatePassword(v *validator.Validator, key, password string, user *data.UserExt) error {
+	v.Check(utf8.RuneCountInString(password) >= app.config.Passwords.MinLength, key, fmt.Sprintf("must be at least %d characters long", app.config.Passwords.MinLength))
+	v.Check(!app.bannedPasswords[strings.ToLower(password)], key, "is too common")
+
+	if user == nil || app.config.Passwords.History <= 0 || len(v.Errors[key]) > 0 {
+		return nil
+	}
+
+	recent := []*types.Password{user.Password}
+
+	history, err := app.models.PasswordHistory.GetPasswordHistory(user.ID, app.config.Passwords.History-1)
+	if err != nil {
+		return err
+	}
+
+	for _, h := range history {
+		recent = append(recent, h.Password)
+	}
+
+	for _, p := range recent {
+		if p == nil || len(p.Hashed) == 0 {
+			continue
+		}
+
+		reused, err := p.Validate(password)
+		if err != nil {
+			return err
+		}
+
+		if reused {
+			v.Add(key, fmt.Sprintf("must not be one of your last %d passwords", app.config.Passwords.History))
+			break
+		}
+	}
+
+	return nil
+}
+
+// savePreviousPassword adds the user's replaced password to their password history
+func (app *application) savePreviousPassword(userID int, previous types.Password) error {
+	if app.config.Passwords.History <= 1 || len(previous.Hashed) == 0 {
+		return nil
+	}
+
+	return app.models.PasswordHistory.InsertPasswordHistory(userID, &previous, app.config.Passwords.History-1)
+}
+
+func (app *application) forgotPassword(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string `json:"email"`
+	}
+
+	err := app.inputJSON(w, r, &input)
+	if err != nil {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	v := validator.NewValidator()
+
+	v.Check(input.Email != "", "email", "must be provided")
+	v.Check(data.EmailRegex.MatchString(input.Email), "email", "must be a valid email address")
+
+	if !v.Valid() {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	// the response is the same whether the user exists or not,
+	// so the endpoint can't be used to find out registered emails
+	response := envelope{"message": "if the account exists, a password reset email has been sent"}
+
+	user, err := app.models.Users.GetUserByEmail(input.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoSuchUser):
+			err = app.outputJSON(w, http.StatusAccepted, response)
+			if err != nil {
+				app.writeInternalServerError(w, r, err)
+			}
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	// directory users and service accounts can't reset their password here, so nothing is sent
+	if *user.ExternalAuth || *user.ServiceAccount {
+		err = app.outputJSON(w, http.StatusAccepted, response)
+		if err != nil {
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.PasswordResets.ExpireAllPasswordResetsByUserID(user.ID)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	reset := &data.PasswordReset{
+		UserID:  &user.ID,
+		Token:   new(types.Token),
+		Expires: helpers.ToPtr(time.Now().UTC().Add(app.config.Passwords.ResetTokenLifetime)),
+	}
+
+	err = reset.Token.NewToken()
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.models.PasswordResets.InsertPasswordReset(reset)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	link := fmt.Sprintf("%s/password/reset?token=%s", app.config.Web.FrontendURL, url.QueryEscape(reset.Token.Plaintext))
+
+	app.sendMail(&mailer.Message{
+		To:      *user.Email,
+		Subject: "Lavurso password reset",
+		Body: fmt.Sprintf("Hello, %s!\n\nA password reset was requested for your account. "+
+			"To choose a new password, open the following link:\n\n%s\n\n"+
+			"The link is valid until %s. If you did not request a password reset, you can ignore this email.\n",
+			*user.Name, link, reset.Expires.Format(time.RFC1123)),
+	})
+
+	err = app.outputJSON(w, http.StatusAccepted, response)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
+
+func (app *application) resetPassword(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	err := app.inputJSON(w, r, &input)
+	if err != nil {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	v := validator.NewValidator()
+
+	v.Check(input.Token != "", "token", "must be provided")
+	v.Check(input.Password != "", "password", "must be provided")
+
+	if !v.Valid() {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	userID, err := app.models.PasswordResets.GetUserIDByResetToken(input.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInvalidResetToken):
+			app.writeErrorResponse(w, r, http.StatusBadRequest, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.models.Users.GetUserByID(userID)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	// the token is only used up once the password passes the policy,
+	// so the user can try another one with the same link
+	err = app.validatePassword(v, "password", input.Password, user)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	_, err = app.models.PasswordResets.UsePasswordReset(input.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInvalidResetToken):
+			app.writeErrorResponse(w, r, http.StatusBadRequest, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	previous := *user.Password
+
+	user.Password.Plaintext = input.Password
+	err = user.Password.CreateHash()
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.models.Users.UpdateUser(user)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.savePreviousPassword(user.ID, previous)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.models.PasswordResets.ExpireAllPasswordResetsByUserID(user.ID)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.models.Sessions.ExpireAllSessionsByUserID(user.ID)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.outputJSON(w, http.StatusOK, envelope{"message": "success"})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
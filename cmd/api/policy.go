@@ -0,0 +1,112 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/annusingmar/lavurso-backend/internal/data"
+	"github.com/annusingmar/lavurso-backend/internal/policy"
+)
+
+func newPolicy(roles map[string][]string) *policy.Policy {
+	if len(roles) == 0 {
+		roles = policy.DefaultRoles
+	}
+
+	p, err := policy.New(roles)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	return p
+}
+
+// can reports whether the session user has the permission for all resources
+func (app *application) can(r *http.Request, permission policy.Permission) bool {
+	user := app.getUserFromContext(r)
+	return app.policy.Allowed(*user.Roles, permission)
+}
+
+// authorize checks whether the session user has the permission for a resource,
+// related tells whether the user is related to it. If not, it writes the error
+// response and the handler should return.
+func (app *application) authorize(w http.ResponseWriter, r *http.Request, permission policy.Permission, related func() (bool, error)) bool {
+	user := app.getUserFromContext(r)
+
+	ok, err := app.policy.Authorize(*user.Roles, permission, related)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return false
+	}
+
+	if !ok {
+		app.notAllowed(w, r)
+		return false
+	}
+
+	return true
+}
+
+// relations used with authorize
+
+func isUser(sessionUser *data.UserExt, userID int) func() (bool, error) {
+	return func() (bool, error) {
+		return sessionUser.ID == userID, nil
+	}
+}
+
+func isTeacherOfJournal(sessionUser *data.UserExt, journal *data.JournalExt) func() (bool, error) {
+	return func() (bool, error) {
+		return journal.IsUserTeacherOfJournal(sessionUser.ID), nil
+	}
+}
+
+// isRelatedToStudent is true for the student themselves, their parents and their class teachers
+func (app *application) isRelatedToStudent(sessionUser *data.UserExt, studentID int) func() (bool, error) {
+	return func() (bool, error) {
+		if sessionUser.ID == studentID {
+			return true, nil
+		}
+		return app.models.Users.IsUserTeacherOrParentOfStudent(studentID, sessionUser.ID)
+	}
+}
+
+func (app *application) isTeacherOrParentOfStudent(sessionUser *data.UserExt, studentID int) func() (bool, error) {
+	return func() (bool, error) {
+		return app.models.Users.IsUserTeacherOrParentOfStudent(studentID, sessionUser.ID)
+	}
+}
+
+func (app *application) isTeacherOfStudent(sessionUser *data.UserExt, studentID int) func() (bool, error) {
+	return func() (bool, error) {
+		return app.models.Users.IsUserTeacherOfStudent(studentID, sessionUser.ID)
+	}
+}
+
+func (app *application) isTeacherOfClass(sessionUser *data.UserExt, classID int) func() (bool, error) {
+	return func() (bool, error) {
+		return app.models.Users.IsUserTeacherOfClass(sessionUser.ID, classID)
+	}
+}
+
+func (app *application) isInThread(sessionUser *data.UserExt, threadID int) func() (bool, error) {
+	return func() (bool, error) {
+		return app.models.Messaging.IsUserInThread(sessionUser.ID, threadID)
+	}
+}
+
+func isCreatorOfThread(sessionUser *data.UserExt, thread *data.ThreadExt) func() (bool, error) {
+	return func() (bool, error) {
+		return *thread.UserID == sessionUser.ID, nil
+	}
+}
+
+// isAuthorOfMessage is true for the message's author, as long as they are still in the thread
+func (app *application) isAuthorOfMessage(sessionUser *data.UserExt, message *data.Message) func() (bool, error) {
+	return func() (bool, error) {
+		if *message.UserID != sessionUser.ID {
+			return false, nil
+		}
+		return app.models.Messaging.IsUserInThread(sessionUser.ID, *message.ThreadID)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"net/http"
 
+	"github.com/annusingmar/lavurso-backend/internal/policy"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
@@ -14,6 +15,7 @@ func (app *application) routes() http.Handler {
 	mux.Use(middleware.Recoverer)
 	mux.Use(middleware.StripSlashes)
 
+	mux.Use(app.resolveIP)
 	mux.Use(app.authenticateSession)
 	mux.Use(app.log)
 
@@ -23,193 +25,278 @@ func (app *application) routes() http.Handler {
 	// authenticate user
 	mux.Post("/authenticate", app.authenticateUser)
 
+	// exchange refresh token for a new session
+	mux.Post("/authenticate/refresh", app.refreshSession)
+
+	// get challenge for logging in with a passkey
+	mux.Post("/authenticate/passkey/begin", app.beginPasskeyLogin)
+
+	// authenticate user with passkey
+	mux.Post("/authenticate/passkey", app.authenticateWithPasskey)
+
+	// list single sign-on providers
+	mux.Get("/authenticate/oidc", app.listOIDCProviders)
+
+	// get provider's login URL
+	mux.Post("/authenticate/oidc/{provider}/begin", app.beginOIDCLogin)
+
+	// authenticate user with code from provider
+	mux.Post("/authenticate/oidc/{provider}", app.authenticateWithOIDC)
+
+	// request password reset email
+	mux.Post("/password/forgot", app.forgotPassword)
+
+	// set new password with reset token
+	mux.Post("/password/reset", app.resetPassword)
+
+	// set password with invitation token
+	mux.Post("/invitations/accept", app.acceptInvitation)
+
 	// requires auth
 	mux.Group(func(mux chi.Router) {
 		mux.Use(app.requireAuthenticatedUser)
+		mux.Use(app.requireAPITokenScope)
+		mux.Use(app.blockImpersonatedWrites)
+
+		// list all users
+		mux.With(app.requirePermission(policy.UserList)).Get("/users", app.listAllUsers)
+
+		// export filtered users as CSV
+		mux.With(app.requirePermission(policy.UserList)).Get("/users/export", app.exportUsers)
+
+		// create new user
+		mux.With(app.requirePermission(policy.UserManage)).Post("/users", app.createUser)
+
+		// create users from CSV
+		mux.With(app.requirePermission(policy.UserManage)).Post("/users/import", app.importUsers)
+
+		// update user
+		mux.With(app.requirePermission(policy.UserManage)).Patch("/users/{id}", app.updateUserAdmin)
+
+		// get user's linked external identities
+		mux.With(app.requirePermission(policy.UserManage)).Get("/users/{id}/identities", app.getExternalIdentitiesForUser)
+
+		// link external identity to user
+		mux.With(app.requirePermission(policy.UserManage)).Post("/users/{id}/identities", app.linkExternalIdentity)
+
+		// unlink external identity from user
+		mux.With(app.requirePermission(policy.UserManage)).Delete("/users/{uid}/identities/{iid}", app.unlinkExternalIdentity)
+
+		// get user's API tokens
+		mux.With(app.requirePermission(policy.UserManage)).Get("/users/{id}/tokens", app.getAPITokensForUser)
+
+		// create API token for user
+		mux.With(app.requirePermission(policy.UserManage)).Post("/users/{id}/tokens", app.createAPITokenForUser)
+
+		// delete user's API token
+		mux.With(app.requirePermission(policy.UserManage)).Delete("/users/{uid}/tokens/{tid}", app.deleteAPITokenForUser)
+
+		// get class by id
+		mux.With(app.requirePermission(policy.ClassManage)).Get("/classes/{id}", app.getClass)
 
-		// requires role 'admin'
-		mux.Group(func(mux chi.Router) {
-			mux.Use(app.requireAdministrator)
+		// create new class
+		mux.With(app.requirePermission(policy.ClassManage)).Post("/classes", app.createClass)
 
-			// list all users
-			mux.Get("/users", app.listAllUsers)
+		// update class
+		mux.With(app.requirePermission(policy.ClassManage)).Patch("/classes/{id}", app.updateClass)
 
-			// create new user
-			mux.Post("/users", app.createUser)
+		// create subject
+		mux.With(app.requirePermission(policy.SubjectManage)).Post("/subjects", app.createSubject)
 
-			// update user
-			mux.Patch("/users/{id}", app.updateUserAdmin)
+		// update subject
+		mux.With(app.requirePermission(policy.SubjectManage)).Patch("/subjects/{id}", app.updateSubject)
 
-			// get class by id
-			mux.Get("/classes/{id}", app.getClass)
+		// delete subject
+		mux.With(app.requirePermission(policy.SubjectManage)).Delete("/subjects/{id}", app.deleteSubject)
 
-			// create new class
-			mux.Post("/classes", app.createClass)
+		// get grade by id
+		mux.With(app.requirePermission(policy.GradeView)).Get("/grades/{id}", app.getGrade)
 
-			// update class
-			mux.Patch("/classes/{id}", app.updateClass)
+		// create grade
+		mux.With(app.requirePermission(policy.GradeManage)).Post("/grades", app.createGrade)
 
-			// create subject
-			mux.Post("/subjects", app.createSubject)
+		// get all groups
+		mux.With(app.requirePermission(policy.GroupView)).Get("/groups", app.getAllGroups)
 
-			// update subject
-			mux.Patch("/subjects/{id}", app.updateSubject)
+		// create group
+		mux.With(app.requirePermission(policy.GroupManage)).Post("/groups", app.createGroup)
 
-			// delete subject
-			mux.Delete("/subjects/{id}", app.deleteSubject)
+		// update grade
+		mux.With(app.requirePermission(policy.GradeManage)).Patch("/grades/{id}", app.updateGrade)
 
-			// get grade by id
-			mux.Get("/grades/{id}", app.getGrade)
+		// get group by id
+		mux.With(app.requirePermission(policy.GroupView)).Get("/groups/{id}", app.getGroup)
 
-			// create grade
-			mux.Post("/grades", app.createGrade)
+		// update group
+		mux.With(app.requirePermission(policy.GroupManage)).Patch("/groups/{id}", app.updateGroup)
 
-			// get all groups
-			mux.Get("/groups", app.getAllGroups)
+		// delete group
+		mux.With(app.requirePermission(policy.GroupManage)).Delete("/groups/{id}", app.deleteGroup)
 
-			// create group
-			mux.Post("/groups", app.createGroup)
+		// add users to group
+		mux.With(app.requirePermission(policy.GroupManage)).Post("/groups/{id}/users", app.addUsersToGroup)
 
-			// update grade
-			mux.Patch("/grades/{id}", app.updateGrade)
+		// delete users from groups
+		mux.With(app.requirePermission(policy.GroupManage)).Delete("/groups/{id}/users", app.removeUsersFromGroup)
 
-			// get group by id
-			mux.Get("/groups/{id}", app.getGroup)
+		// get users by group id
+		mux.With(app.requirePermission(policy.GroupView)).Get("/groups/{id}/users", app.getUsersForGroup)
 
-			// update group
-			mux.Patch("/groups/{id}", app.updateGroup)
+		// get all journals
+		mux.With(app.requirePermission(policy.JournalList)).Get("/journals", app.listAllJournals)
 
-			// delete group
-			mux.Delete("/groups/{id}", app.deleteGroup)
+		// delete journal
+		mux.With(app.requirePermission(policy.JournalDelete)).Delete("/journals/{id}", app.deleteJournal)
 
-			// add users to group
-			mux.Post("/groups/{id}/users", app.addUsersToGroup)
+		// add parent to student
+		mux.With(app.requirePermission(policy.UserManage)).Put("/students/{id}/parents", app.addParentToStudent)
 
-			// delete users from groups
-			mux.Delete("/groups/{id}/users", app.removeUsersFromGroup)
+		// remove parent from student
+		mux.With(app.requirePermission(policy.UserManage)).Delete("/students/{id}/parents", app.removeParentFromStudent)
 
-			// get users by group id
-			mux.Get("/groups/{id}/users", app.getUsersForGroup)
+		// student's class memberships in all years
+		mux.With(app.requirePermission(policy.UserManage)).Get("/students/{id}/memberships", app.getClassMembershipsForStudent)
 
-			// get all journals
-			mux.Get("/journals", app.listAllJournals)
+		// move student to another class from a date
+		mux.With(app.requirePermission(policy.UserManage)).Post("/students/{id}/transfer", app.transferStudent)
 
-			// delete journal
-			mux.Delete("/journals/{id}", app.deleteJournal)
+		// new year
+		mux.With(app.requirePermission(policy.YearManage)).Post("/years/new", app.newYear)
 
-			// add parent to student
-			mux.Put("/students/{id}/parents", app.addParentToStudent)
+		// latest new year and until when it can be undone
+		mux.With(app.requirePermission(policy.YearManage)).Get("/years/rollover", app.getLatestRollover)
 
-			// remove parent from student
-			mux.Delete("/students/{id}/parents", app.removeParentFromStudent)
+		// undo latest new year
+		mux.With(app.requirePermission(policy.YearManage)).Post("/years/rollover/undo", app.undoRollover)
 
-			// new year
-			mux.Post("/years/new", app.newYear)
+		mux.With(app.requirePermission(policy.ClassManage)).Get("/classes/{id}/years", app.getYearsForClass)
 
-			mux.Get("/classes/{id}/years", app.getYearsForClass)
+		mux.With(app.requirePermission(policy.ClassManage)).Put("/classes/{id}/years", app.setYearsForClass)
 
-			mux.Put("/classes/{id}/years", app.setYearsForClass)
+		// get all sessions for user
+		mux.With(app.requirePermission(policy.SessionManage)).Get("/users/{id}/sessions", app.allSessionsForUser)
 
-			// get all sessions for user
-			mux.Get("/users/{id}/sessions", app.allSessionsForUser)
+		// list pending invitations
+		mux.With(app.requirePermission(policy.UserManage)).Get("/invitations", app.listInvitations)
 
-			// delete all sesions for user
-			mux.Delete("/users/{id}/sessions", app.expireAllSessionsForUser)
+		// resend invitation with a new link
+		mux.With(app.requirePermission(policy.UserManage)).Post("/invitations/{id}/resend", app.resendInvitation)
 
-			mux.Get("/logs", app.getLogs)
-		})
+		// revoke invitation
+		mux.With(app.requirePermission(policy.UserManage)).Delete("/invitations/{id}", app.revokeInvitation)
 
-		// requires at least role 'teacher'
-		mux.Group(func(mux chi.Router) {
-			mux.Use(app.requireTeacher)
+		// start a session as the user
+		mux.With(app.requirePermission(policy.UserImpersonate)).Post("/users/{id}/impersonate", app.impersonateUser)
 
-			// create journal
-			mux.Post("/journals", app.createJournal)
+		// export all data about the user
+		mux.With(app.requirePermission(policy.UserManage)).Get("/users/{id}/export", app.exportUser)
 
-			// get journal by id
-			mux.Get("/journals/{id}", app.getJournal)
+		// anonymize archived user whose retention period has ended
+		mux.With(app.requirePermission(policy.UserManage)).Post("/users/{id}/anonymize", app.anonymizeUser)
 
-			// update journal
-			mux.Patch("/journals/{id}", app.updateJournal)
+		// list anonymized users
+		mux.With(app.requirePermission(policy.UserManage)).Get("/anonymizations", app.listAnonymizations)
 
-			// get journals for teacher
-			mux.Get("/teachers/{id}/journals", app.getJournalsForTeacher)
+		// delete all sesions for user
+		mux.With(app.requirePermission(policy.SessionManage)).Delete("/users/{id}/sessions", app.expireAllSessionsForUser)
 
-			// get classes for teacher
-			mux.Get("/teachers/{id}/classes", app.getClassesForTeacher)
+		mux.With(app.requirePermission(policy.LogView)).Get("/logs", app.getLogs)
 
-			// get students in class
-			mux.Get("/classes/{id}/students", app.getStudentsInClass)
+		// get active login lockouts
+		mux.With(app.requirePermission(policy.LockoutManage)).Get("/lockouts", app.listLockouts)
 
-			// get users for journal
-			mux.Get("/journals/{id}/students", app.getStudentsForJournal)
+		// clear login lockout
+		mux.With(app.requirePermission(policy.LockoutManage)).Delete("/lockouts/{id}", app.clearLockout)
 
-			// add users to journal
-			mux.Post("/journals/{id}/students", app.addStudentsToJournal)
+		// create journal
+		mux.With(app.requirePermission(policy.JournalCreate)).Post("/journals", app.createJournal)
 
-			// remove user from journal
-			mux.Delete("/journals/{id}/students", app.removeStudentFromJournal)
+		// get journal by id
+		mux.Get("/journals/{id}", app.getJournal)
 
-			// get lesson by id
-			mux.Get("/lessons/{id}", app.getLesson)
+		// update journal
+		mux.Patch("/journals/{id}", app.updateJournal)
 
-			// create lesson
-			mux.Post("/lessons", app.createLesson)
+		// get journals for teacher
+		mux.Get("/teachers/{id}/journals", app.getJournalsForTeacher)
 
-			// update lesson
-			mux.Patch("/lessons/{id}", app.updateLesson)
+		// get classes for teacher
+		mux.Get("/teachers/{id}/classes", app.getClassesForTeacher)
 
-			// delete lesson
-			mux.Delete("/lessons/{id}", app.deleteLesson)
+		// get students in class
+		mux.Get("/classes/{id}/students", app.getStudentsInClass)
 
-			// get all grades
-			mux.Get("/grades", app.listAllGrades)
+		// get users for journal
+		mux.Get("/journals/{id}/students", app.getStudentsForJournal)
 
-			// get lessons for journal
-			mux.Get("/journals/{id}/lessons", app.getLessonsForJournal)
+		// add users to journal
+		mux.Post("/journals/{id}/students", app.addStudentsToJournal)
 
-			// get assignment by id
-			mux.Get("/assignments/{id}", app.getAssignment)
+		// remove user from journal
+		mux.Delete("/journals/{id}/students", app.removeStudentFromJournal)
 
-			// get all assignments for journal
-			mux.Get("/journals/{id}/assignments", app.getAssignmentsForJournal)
+		// get lesson by id
+		mux.Get("/lessons/{id}", app.getLesson)
 
-			// create assignment
-			mux.Post("/assignments", app.createAssignment)
+		// create lesson
+		mux.Post("/lessons", app.createLesson)
 
-			// update assignment
-			mux.Patch("/assignments/{id}", app.updateAssignment)
+		// update lesson
+		mux.Patch("/lessons/{id}", app.updateLesson)
 
-			// delete assignment
-			mux.Delete("/assignments/{id}", app.deleteAssignment)
+		// delete lesson
+		mux.Delete("/lessons/{id}", app.deleteLesson)
 
-			// get students and marks for lesson
-			mux.Get("/lessons/{id}/marks", app.getMarksForLesson)
+		// get all grades
+		mux.With(app.requirePermission(policy.GradeView)).Get("/grades", app.listAllGrades)
 
-			// save marks for lesson
-			mux.Patch("/lessons/{id}/marks", app.setMarksForLesson)
+		// get lessons for journal
+		mux.Get("/journals/{id}/lessons", app.getLessonsForJournal)
 
-			// get course + all lessons marks for course
-			mux.Get("/journals/{jid}/courses/{course}/marks", app.getMarksForCourse)
+		// get assignment by id
+		mux.Get("/assignments/{id}", app.getAssignment)
 
-			// save marks for course
-			mux.Patch("/journals/{jid}/courses/{course}/marks", app.setMarksForCourse)
+		// get all assignments for journal
+		mux.Get("/journals/{id}/assignments", app.getAssignmentsForJournal)
 
-			// get subject + all course marks for journal
-			mux.Get("/journals/{jid}/subject/marks", app.getMarksForJournalSubject)
+		// create assignment
+		mux.Post("/assignments", app.createAssignment)
 
-			// save marks for journal's subject
-			mux.Patch("/journals/{jid}/subject/marks", app.setMarksForJournalSubject)
+		// update assignment
+		mux.Patch("/assignments/{id}", app.updateAssignment)
 
-			// list all subjects
-			mux.Get("/subjects", app.listAllSubjects)
+		// delete assignment
+		mux.Delete("/assignments/{id}", app.deleteAssignment)
 
-			// list all classes
-			mux.Get("/classes", app.listAllClasses)
-		})
+		// get students and marks for lesson
+		mux.Get("/lessons/{id}/marks", app.getMarksForLesson)
 
-		// search for user with query param 'name' (minimum 4 characters)
-		mux.Get("/users/search", app.searchUser)
+		// save marks for lesson
+		mux.Patch("/lessons/{id}/marks", app.setMarksForLesson)
+
+		// get course + all lessons marks for course
+		mux.Get("/journals/{jid}/courses/{course}/marks", app.getMarksForCourse)
+
+		// save marks for course
+		mux.Patch("/journals/{jid}/courses/{course}/marks", app.setMarksForCourse)
+
+		// get subject + all course marks for journal
+		mux.Get("/journals/{jid}/subject/marks", app.getMarksForJournalSubject)
+
+		// save marks for journal's subject
+		mux.Patch("/journals/{jid}/subject/marks", app.setMarksForJournalSubject)
+
+		// list all subjects
+		mux.With(app.requirePermission(policy.SubjectView)).Get("/subjects", app.listAllSubjects)
+
+		// list all classes
+		mux.With(app.requirePermission(policy.ClassList)).Get("/classes", app.listAllClasses)
+
+		// search for users with query param 'q', ranked by similarity
+		mux.With(app.requirePermission(policy.UserSearch)).Get("/users/search", app.searchUser)
+
+		// find user by ID code
+		mux.With(app.requirePermission(policy.UserManage)).Get("/users/id_code/{code}", app.getUserByIDCode)
 
 		// get all assignments for student
 		mux.Get("/students/{id}/assignments", app.getAssignmentsForStudent)
@@ -245,7 +332,7 @@ func (app *application) routes() http.Handler {
 		mux.Get("/me/unread", app.userHasUnread)
 
 		// create thread
-		mux.Post("/threads", app.createThread)
+		mux.With(app.requirePermission(policy.ThreadCreate)).Post("/threads", app.createThread)
 
 		// lock thread
 		mux.Put("/threads/{id}/lock", app.lockThread)
@@ -299,9 +386,9 @@ func (app *application) routes() http.Handler {
 		mux.Get("/me", app.myInfo)
 
 		// all years
-		mux.Get("/years", app.getAllYears)
+		mux.With(app.requirePermission(policy.YearView)).Get("/years", app.getAllYears)
 
-		// years for student's class
+		// years the student has been in a class
 		mux.Get("/students/{id}/years", app.getYearsForStudent)
 
 		// start 2fa (generate secret)
@@ -313,6 +400,42 @@ func (app *application) routes() http.Handler {
 		// disable 2fa
 		mux.Delete("/me/2fa", app.disable2FA)
 
+		// regenerate 2fa recovery codes
+		mux.Post("/me/2fa/recovery", app.regenerateRecoveryCodes)
+
+		// list passkeys
+		mux.Get("/me/passkeys", app.listPasskeys)
+
+		// start registering a passkey
+		mux.Post("/me/passkeys/begin", app.beginPasskeyRegistration)
+
+		// finish registering a passkey
+		mux.Post("/me/passkeys", app.finishPasskeyRegistration)
+
+		// delete passkey
+		mux.Delete("/me/passkeys/{id}", app.deletePasskey)
+
+		// list own API tokens
+		mux.Get("/me/tokens", app.listAPITokens)
+
+		// create API token
+		mux.Post("/me/tokens", app.createAPIToken)
+
+		// delete API token
+		mux.Delete("/me/tokens/{id}", app.deleteAPIToken)
+
+		// list own active sessions
+		mux.Get("/me/sessions", app.listOwnSessions)
+
+		// log out of all other sessions
+		mux.Delete("/me/sessions", app.expireOwnOtherSessions)
+
+		// list sessions admins have started as the user
+		mux.Get("/me/impersonations", app.listOwnImpersonations)
+
+		// export all data about the user
+		mux.Get("/me/export", app.exportOwnData)
+
 		// logout
 		mux.Post("/me/logout", app.logout)
 	})
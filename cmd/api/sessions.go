@@ -6,6 +6,9 @@ import (
 	"strconv"
 
 	"github.com/annusingmar/lavurso-backend/internal/data"
+	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/annusingmar/lavurso-backend/internal/policy"
+	"github.com/annusingmar/lavurso-backend/internal/useragent"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -89,13 +92,8 @@ func (app *application) expireSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	switch *sessionUser.Role {
-	case data.RoleAdministrator:
-	default:
-		if sessionUser.ID != *session.UserID {
-			app.notAllowed(w, r)
-			return
-		}
+	if !app.authorize(w, r, policy.SessionRevoke, isUser(sessionUser, *session.UserID)) {
+		return
 	}
 
 	err = app.models.Sessions.ExpireSessionByID(session.ID)
@@ -110,6 +108,50 @@ func (app *application) expireSession(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (app *application) listOwnSessions(w http.ResponseWriter, r *http.Request) {
+	sessionUser := app.getUserFromContext(r)
+
+	sessions, err := app.models.Sessions.GetActiveSessionsByUserID(sessionUser.ID)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	for _, s := range sessions {
+		s.Current = helpers.ToPtr(sessionUser.SessionID != nil && s.ID == *sessionUser.SessionID)
+		if s.LoginBrowser != nil {
+			s.Agent = helpers.ToPtr(useragent.Parse(*s.LoginBrowser))
+		}
+	}
+
+	err = app.outputJSON(w, http.StatusOK, envelope{"sessions": sessions})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
+
+// expireOwnOtherSessions logs the user out everywhere except the current session,
+// with an API token there is no current session, so all of them are expired
+func (app *application) expireOwnOtherSessions(w http.ResponseWriter, r *http.Request) {
+	sessionUser := app.getUserFromContext(r)
+
+	var err error
+	if sessionUser.SessionID != nil {
+		err = app.models.Sessions.ExpireAllSessionsByUserIDExceptOne(sessionUser.ID, *sessionUser.SessionID)
+	} else {
+		err = app.models.Sessions.ExpireAllSessionsByUserID(sessionUser.ID)
+	}
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.outputJSON(w, http.StatusOK, envelope{"message": "success"})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
+
 func (app *application) logout(w http.ResponseWriter, r *http.Request) {
 	sessionUser := app.getUserFromContext(r)
 
@@ -119,6 +161,8 @@ func (app *application) logout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	app.clearSessionCookies(w)
+
 	err = app.outputJSON(w, http.StatusOK, envelope{"message": "success"})
 	if err != nil {
 		app.writeInternalServerError(w, r, err)
@@ -9,6 +9,7 @@ import (
 
 	"github.com/annusingmar/lavurso-backend/internal/data"
 	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/annusingmar/lavurso-backend/internal/policy"
 	"github.com/annusingmar/lavurso-backend/internal/types"
 	"github.com/go-chi/chi/v5"
 )
@@ -39,16 +40,8 @@ func (app *application) getLatestMarksLessonsForStudent(w http.ResponseWriter, r
 		return
 	}
 
-	if sessionUser.ID != student.ID && *sessionUser.Role != data.RoleAdministrator {
-		ok, err := app.models.Users.IsUserTeacherOrParentOfStudent(student.ID, sessionUser.ID)
-		if err != nil {
-			app.writeInternalServerError(w, r, err)
-			return
-		}
-		if !ok {
-			app.notAllowed(w, r)
-			return
-		}
+	if !app.authorize(w, r, policy.StudentView, app.isRelatedToStudent(sessionUser, student.ID)) {
+		return
 	}
 
 	var from *types.Date
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"encoding/csv"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -11,43 +13,230 @@ import (
 
 	"github.com/annusingmar/lavurso-backend/internal/data"
 	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/annusingmar/lavurso-backend/internal/isikukood"
+	"github.com/annusingmar/lavurso-backend/internal/policy"
 	"github.com/annusingmar/lavurso-backend/internal/types"
 	"github.com/annusingmar/lavurso-backend/internal/validator"
 	"github.com/go-chi/chi/v5"
 )
 
+// readUserFilter reads the filters and sorting of the user list from query params:
+// role, class_id, active, archived (false if not given), totp_enabled,
+// created_after and created_before as dates, and sort as a key of data.UserSorts,
+// prefixed with '-' for descending order
+func (app *application) readUserFilter(v *validator.Validator, qs url.Values) (filter *data.UserFilter, sort string, descending bool) {
+	filter = &data.UserFilter{
+		Archived: helpers.ToPtr(false),
+	}
+
+	readBool := func(key string) *bool {
+		switch qs.Get(key) {
+		case "":
+			return nil
+		case "true":
+			return helpers.ToPtr(true)
+		case "false":
+			return helpers.ToPtr(false)
+		default:
+			v.Add(key, "must be true or false")
+			return nil
+		}
+	}
+
+	readDate := func(key string) *time.Time {
+		if qs.Get(key) == "" {
+			return nil
+		}
+		date, err := types.ParseDate(qs.Get(key))
+		if err != nil {
+			v.Add(key, err.Error())
+			return nil
+		}
+		return date.Time
+	}
+
+	if role := qs.Get("role"); role != "" {
+		v.Check(app.policy.HasRole(role), "role", "must be valid role")
+		filter.Role = &role
+	}
+
+	if qs.Get("class_id") != "" {
+		classID, err := strconv.Atoi(qs.Get("class_id"))
+		v.Check(err == nil, "class_id", "must be an integer")
+		filter.ClassID = &classID
+	}
+
+	filter.Active = readBool("active")
+	if archived := readBool("archived"); archived != nil {
+		filter.Archived = archived
+	}
+	filter.TotpEnabled = readBool("totp_enabled")
+
+	filter.CreatedAfter = readDate("created_after")
+	// the day given in created_before is included
+	if before := readDate("created_before"); before != nil {
+		filter.CreatedBefore = helpers.ToPtr(before.AddDate(0, 0, 1))
+	}
+
+	sort = strings.TrimPrefix(qs.Get("sort"), "-")
+	descending = strings.HasPrefix(qs.Get("sort"), "-")
+	if sort == "" {
+		sort = "id"
+	}
+	_, ok := data.UserSorts[sort]
+	v.Check(ok, "sort", "must be a valid sort")
+
+	return filter, sort, descending
+}
+
 func (app *application) listAllUsers(w http.ResponseWriter, r *http.Request) {
-	var archived bool
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
 
-	archivedParam := r.URL.Query().Get("archived")
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 0 {
+		limit = 50
+	}
 
-	if archivedParam == "true" {
-		archived = true
-	} else {
-		archived = false
+	v := validator.NewValidator()
+
+	filter, sort, descending := app.readUserFilter(v, r.URL.Query())
+
+	if !v.Valid() {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, v.Errors)
+		return
 	}
 
-	users, err := app.models.Users.AllUsers(archived)
+	users, err := app.models.Users.AllUsers(filter, sort, descending, page, limit)
 	if err != nil {
 		app.writeInternalServerError(w, r, err)
 		return
 	}
 
-	err = app.outputJSON(w, http.StatusOK, envelope{"users": users})
+	err = app.outputJSON(w, http.StatusOK, envelope{"result": users})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
+
+// exportUsers responds with all users matching the user list's filters as CSV,
+// with the same columns that importUsers accepts and some more
+func (app *application) exportUsers(w http.ResponseWriter, r *http.Request) {
+	v := validator.NewValidator()
+
+	filter, sort, descending := app.readUserFilter(v, r.URL.Query())
+
+	if !v.Valid() {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	users, err := app.models.Users.AllUsers(filter, sort, descending, 1, 0)
 	if err != nil {
 		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="users.csv"`)
+	w.WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(w)
+
+	writer.Write([]string{"id", "name", "email", "id_code", "phone", "role", "roles", "class", "active", "archived", "totp_enabled", "created_at"})
+
+	for _, u := range users.Users {
+		var idCode, phone, class string
+		if u.IDCode != nil {
+			idCode = strconv.FormatInt(*u.IDCode, 10)
+		}
+		if u.PhoneNumber != nil {
+			phone = *u.PhoneNumber
+		}
+		if u.Student != nil && u.Student.Class != nil && u.Student.Class.Name != nil {
+			class = *u.Student.Class.Name
+		}
+
+		writer.Write([]string{
+			strconv.Itoa(u.ID),
+			csvText(*u.Name),
+			csvText(*u.Email),
+			idCode,
+			csvText(phone),
+			*u.Role,
+			strings.Join(*u.Roles, " "),
+			csvText(class),
+			strconv.FormatBool(*u.Active),
+			strconv.FormatBool(*u.Archived),
+			strconv.FormatBool(*u.TotpEnabled),
+			u.CreatedAt.Format(time.RFC3339),
+		})
+	}
+
+	writer.Flush()
+
+	// the status is already written, so errors can only be logged
+	if err := writer.Error(); err != nil {
+		app.errorLogger.Println(err)
+	}
+}
+
+// csvText keeps spreadsheets from running user-entered text as a formula
+func csvText(s string) string {
+	if s != "" && strings.ContainsAny(s[:1], "=+-@\t\r") {
+		return "'" + s
 	}
+	return s
 }
 
+// searchUser finds users similar to query param 'q', with optional filters 'role' and 'class_id'.
+// Emails and ID codes are only searched for users who can list all users.
 func (app *application) searchUser(w http.ResponseWriter, r *http.Request) {
-	name := strings.TrimSpace(r.URL.Query().Get("name"))
+	qs := r.URL.Query()
+
+	search := &data.UserSearch{
+		Query:     strings.TrimSpace(qs.Get("q")),
+		Private:   app.can(r, policy.UserList),
+		Threshold: app.config.Search.SimilarityThreshold,
+		Limit:     app.config.Search.MaxResults,
+	}
+
+	// 'name' was the query param before emails and ID codes were searched
+	if search.Query == "" {
+		search.Query = strings.TrimSpace(qs.Get("name"))
+	}
+
+	v := validator.NewValidator()
 
-	if utf8.RuneCountInString(name) < 4 {
-		app.writeErrorResponse(w, r, http.StatusBadRequest, "not enough characters provided")
+	v.Check(utf8.RuneCountInString(search.Query) >= app.config.Search.MinLength, "q", fmt.Sprintf("must be at least %d characters long", app.config.Search.MinLength))
+
+	if role := qs.Get("role"); role != "" {
+		v.Check(app.policy.HasRole(role), "role", "must be valid role")
+		search.Role = &role
+	}
+
+	if qs.Get("class_id") != "" {
+		classID, err := strconv.Atoi(qs.Get("class_id"))
+		v.Check(err == nil, "class_id", "must be an integer")
+		search.ClassID = &classID
+	}
+
+	if qs.Get("limit") != "" {
+		limit, err := strconv.Atoi(qs.Get("limit"))
+		v.Check(err == nil && limit > 0, "limit", "must be a positive integer")
+		if limit < search.Limit {
+			search.Limit = limit
+		}
+	}
+
+	if !v.Valid() {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, v.Errors)
 		return
 	}
 
-	result, err := app.models.Users.SearchUser(name)
+	result, err := app.models.Users.SearchUser(search)
 	if err != nil {
 		app.writeInternalServerError(w, r, err)
 		return
@@ -59,16 +248,85 @@ func (app *application) searchUser(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// getUserByIDCode lets admins find existing users, e.g. parents, before creating duplicates
+func (app *application) getUserByIDCode(w http.ResponseWriter, r *http.Request) {
+	idCode, err := strconv.ParseInt(chi.URLParam(r, "code"), 10, 64)
+	if err != nil || !isikukood.Valid(idCode) {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, "invalid ID code")
+		return
+	}
+
+	user, err := app.models.Users.GetUserByIDCode(idCode)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoSuchUser):
+			app.writeErrorResponse(w, r, http.StatusNotFound, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	err = app.outputJSON(w, http.StatusOK, envelope{"user": user})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
+
+// checkIDCode validates the ID code and returns the birth date to save with it,
+// which is taken from the code if it wasn't given
+func checkIDCode(v *validator.Validator, idCode *int64, birthDate *types.Date) *types.Date {
+	if idCode == nil {
+		return birthDate
+	}
+
+	date, err := isikukood.BirthDate(*idCode)
+	if err != nil {
+		v.Add("id_code", err.Error())
+		return birthDate
+	}
+
+	if birthDate == nil || birthDate.Time == nil {
+		return &types.Date{Time: &date}
+	}
+
+	v.Check(birthDate.Time.Equal(date), "birth_date", "must match the birth date in the ID code")
+
+	return birthDate
+}
+
+// checkRoles validates the roles of a user besides their primary role
+// and returns all of their roles, starting with the primary role
+func (app *application) checkRoles(v *validator.Validator, role string, roles []string) types.Roles {
+	all := types.Roles{role}
+
+	for _, r := range roles {
+		v.Check(app.policy.HasRole(r), "roles", "must be valid roles")
+		all = all.With(r)
+	}
+
+	if role == data.RoleStudent {
+		v.Check(len(all) == 1, "roles", "students can't have other roles")
+	} else {
+		v.Check(!all.Has(data.RoleStudent), "roles", "only students can have the student role")
+	}
+
+	return all
+}
+
 func (app *application) createUser(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Name        string      `json:"name"`
-		Email       string      `json:"email"`
-		Password    string      `json:"password"`
-		PhoneNumber *string     `json:"phone_number"`
-		IdCode      *int64      `json:"id_code"`
-		BirthDate   *types.Date `json:"birth_date"`
-		Role        string      `json:"role"`
-		ClassID     *int        `json:"class_id"`
+		Name           string      `json:"name"`
+		Email          string      `json:"email"`
+		Password       string      `json:"password"`
+		PhoneNumber    *string     `json:"phone_number"`
+		IdCode         *int64      `json:"id_code"`
+		BirthDate      *types.Date `json:"birth_date"`
+		Role           string      `json:"role"`
+		Roles          []string    `json:"roles"`
+		ClassID        *int        `json:"class_id"`
+		ExternalAuth   bool        `json:"external_auth"`
+		ServiceAccount bool        `json:"service_account"`
 	}
 
 	err := app.inputJSON(w, r, &input)
@@ -83,7 +341,7 @@ func (app *application) createUser(w http.ResponseWriter, r *http.Request) {
 	v.Check(input.Email != "", "email", "must be provided")
 	v.Check(data.EmailRegex.MatchString(input.Email), "email", "must be a valid email address")
 	v.Check(input.PhoneNumber == nil || *input.PhoneNumber != "", "phone_number", "must not be empty")
-	v.Check(input.IdCode == nil || len(fmt.Sprint(*input.IdCode)) == 11, "id_code", "must be 11 digits long")
+	input.BirthDate = checkIDCode(v, input.IdCode, input.BirthDate)
 
 	if input.BirthDate == nil || input.BirthDate.Time == nil {
 		input.BirthDate = new(types.Date)
@@ -94,9 +352,17 @@ func (app *application) createUser(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	v.Check(input.Role == data.RoleAdministrator || input.Role == data.RoleTeacher || input.Role == data.RoleParent || input.Role == data.RoleStudent, "role", "must be valid role")
+	v.Check(app.policy.HasRole(input.Role), "role", "must be valid role")
+	roles := app.checkRoles(v, input.Role, input.Roles)
 
-	v.Check(input.Password != "", "password", "must be provided")
+	if input.Password != "" {
+		err = app.validatePassword(v, "password", input.Password, nil)
+		if err != nil {
+			app.writeInternalServerError(w, r, err)
+			return
+		}
+	}
+	v.Check(!input.ServiceAccount || input.Role != data.RoleStudent, "service_account", "can't be a student")
 
 	if input.Role == data.RoleStudent {
 		v.Check(input.ClassID != nil, "class_id", "must be provided")
@@ -126,15 +392,30 @@ func (app *application) createUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	user := &data.User{
-		Name:        &input.Name,
-		Email:       &input.Email,
-		Password:    &types.Password{Plaintext: input.Password},
-		PhoneNumber: input.PhoneNumber,
-		IDCode:      input.IdCode,
-		BirthDate:   input.BirthDate,
-		Role:        &input.Role,
-		ClassID:     classID,
-		TotpEnabled: helpers.ToPtr(false),
+		Name:           &input.Name,
+		Email:          &input.Email,
+		Password:       &types.Password{Plaintext: input.Password},
+		PhoneNumber:    input.PhoneNumber,
+		IDCode:         input.IdCode,
+		BirthDate:      input.BirthDate,
+		Role:           &input.Role,
+		Roles:          &roles,
+		ClassID:        classID,
+		TotpEnabled:    helpers.ToPtr(false),
+		ExternalAuth:   &input.ExternalAuth,
+		ServiceAccount: &input.ServiceAccount,
+	}
+
+	// users without a password are invited to choose one,
+	// directory users and service accounts don't need one, but still get a hash
+	invite := input.Password == "" && !input.ExternalAuth && !input.ServiceAccount
+
+	if user.Password.Plaintext == "" {
+		user.Password.Plaintext, err = randomString()
+		if err != nil {
+			app.writeInternalServerError(w, r, err)
+			return
+		}
 	}
 
 	err = user.Password.CreateHash()
@@ -143,7 +424,16 @@ func (app *application) createUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = app.models.Users.InsertUser(user)
+	var invitation *data.Invitation
+	if invite {
+		invitation, err = app.newInvitation(r)
+		if err != nil {
+			app.writeInternalServerError(w, r, err)
+			return
+		}
+	}
+
+	err = app.models.Users.InsertUser(user, invitation)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEmailAlreadyExists) || errors.Is(err, data.ErrIDCodeAlreadyExists):
@@ -154,6 +444,10 @@ func (app *application) createUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if invitation != nil {
+		app.sendInvitationMail(user, invitation)
+	}
+
 	err = app.outputJSON(w, http.StatusCreated, envelope{"message": "success"})
 	if err != nil {
 		app.writeInternalServerError(w, r, err)
@@ -170,8 +464,7 @@ func (app *application) getUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if userID != sessionUser.ID && *sessionUser.Role != data.RoleAdministrator {
-		app.notAllowed(w, r)
+	if !app.authorize(w, r, policy.UserView, isUser(sessionUser, userID)) {
 		return
 	}
 
@@ -212,16 +505,18 @@ func (app *application) updateUserAdmin(w http.ResponseWriter, r *http.Request)
 	}
 
 	var input struct {
-		Name        *string     `json:"name"`
-		Email       *string     `json:"email"`
-		Password    *string     `json:"password"`
-		PhoneNumber *string     `json:"phone_number"`
-		IdCode      *int64      `json:"id_code"`
-		BirthDate   *types.Date `json:"birth_date"`
-		ClassID     *int        `json:"class_id"`
-		Active      *bool       `json:"active"`
-		TotpEnabled *bool       `json:"totp_enabled"`
-		Archived    *bool       `json:"archived"`
+		Name         *string     `json:"name"`
+		Email        *string     `json:"email"`
+		Password     *string     `json:"password"`
+		PhoneNumber  *string     `json:"phone_number"`
+		IdCode       *int64      `json:"id_code"`
+		BirthDate    *types.Date `json:"birth_date"`
+		Roles        *[]string   `json:"roles"`
+		ClassID      *int        `json:"class_id"`
+		Active       *bool       `json:"active"`
+		TotpEnabled  *bool       `json:"totp_enabled"`
+		Archived     *bool       `json:"archived"`
+		ExternalAuth *bool       `json:"external_auth"`
 	}
 
 	err = app.inputJSON(w, r, &input)
@@ -237,7 +532,20 @@ func (app *application) updateUserAdmin(w http.ResponseWriter, r *http.Request)
 	v.Check(input.Email == nil || data.EmailRegex.MatchString(*input.Email), "email", "must be a valid email address")
 	v.Check(input.Password == nil || *input.Password != "", "password", "must not be empty")
 	v.Check(input.PhoneNumber == nil || *input.PhoneNumber != "", "phone_number", "must not be empty")
-	v.Check(input.IdCode == nil || len(fmt.Sprint(*input.IdCode)) == 11, "id_code", "must be 11 digits long")
+	input.BirthDate = checkIDCode(v, input.IdCode, input.BirthDate)
+
+	var roles types.Roles
+	if input.Roles != nil {
+		roles = app.checkRoles(v, *user.Role, *input.Roles)
+	}
+
+	if input.Password != nil && *input.Password != "" {
+		err = app.validatePassword(v, "password", *input.Password, user)
+		if err != nil {
+			app.writeInternalServerError(w, r, err)
+			return
+		}
+	}
 
 	if !v.Valid() {
 		app.writeErrorResponse(w, r, http.StatusBadRequest, v.Errors)
@@ -252,6 +560,8 @@ func (app *application) updateUserAdmin(w http.ResponseWriter, r *http.Request)
 		user.Email = input.Email
 	}
 
+	previous := *user.Password
+
 	if input.Password != nil {
 		user.Password.Plaintext = *input.Password
 		err = user.Password.CreateHash()
@@ -274,6 +584,8 @@ func (app *application) updateUserAdmin(w http.ResponseWriter, r *http.Request)
 		user.BirthDate = new(types.Date)
 	}
 
+	var transferYear *data.Year
+
 	if input.ClassID != nil && *user.Role == data.RoleStudent {
 		class, err := app.models.Classes.GetClassByID(*input.ClassID)
 		if err != nil {
@@ -286,28 +598,76 @@ func (app *application) updateUserAdmin(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
+		// a new class is also a transfer in the current year, if there is one
+		if user.ClassID == nil || *user.ClassID != class.ID {
+			current, err := app.models.Years.GetCurrentYear()
+			if err != nil {
+				app.writeInternalServerError(w, r, err)
+				return
+			}
+			if current != nil {
+				transferYear = &current.Year
+			}
+		}
+
 		user.ClassID = &class.ID
 	}
 
-	if input.Archived != nil {
+	if input.Roles != nil {
+		user.Roles = &roles
+	}
+
+	if input.Archived != nil && *input.Archived != *user.Archived {
 		user.Archived = input.Archived
+		if *input.Archived {
+			user.ArchivedAt = helpers.ToPtr(time.Now().UTC())
+		} else {
+			user.ArchivedAt = nil
+		}
 	}
 
 	if input.TotpEnabled != nil && *user.HasTOTPSecret {
 		user.TotpEnabled = input.TotpEnabled
 	}
 
-	err = app.models.Users.UpdateUser(user)
+	if input.ExternalAuth != nil {
+		user.ExternalAuth = input.ExternalAuth
+	}
+
+	if transferYear != nil {
+		today := &types.Date{Time: helpers.ToPtr(time.Now().UTC())}
+		err = app.models.Users.UpdateUserWithTransfer(user, transferYear, today)
+	} else {
+		err = app.models.Users.UpdateUser(user)
+	}
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEmailAlreadyExists) || errors.Is(err, data.ErrIDCodeAlreadyExists):
 			app.writeErrorResponse(w, r, http.StatusConflict, err.Error())
+		case errors.Is(err, data.ErrClassNotInYear) || errors.Is(err, data.ErrInvalidTransferDate):
+			app.writeErrorResponse(w, r, http.StatusBadRequest, err.Error())
 		default:
 			app.writeInternalServerError(w, r, err)
 		}
 		return
 	}
 
+	if input.Password != nil {
+		err = app.savePreviousPassword(user.ID, previous)
+		if err != nil {
+			app.writeInternalServerError(w, r, err)
+			return
+		}
+	}
+
+	if user.TotpEnabled != nil && !*user.TotpEnabled {
+		err = app.models.RecoveryCodes.DeleteRecoveryCodesForUser(user.ID)
+		if err != nil {
+			app.writeInternalServerError(w, r, err)
+			return
+		}
+	}
+
 	err = app.outputJSON(w, http.StatusOK, envelope{"message": "success"})
 	if err != nil {
 		app.writeInternalServerError(w, r, err)
@@ -383,6 +743,11 @@ func (app *application) changeUserPassword(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	if *user.ExternalAuth {
+		app.writeErrorResponse(w, r, http.StatusConflict, ErrExternalPassword.Error())
+		return
+	}
+
 	correct, err := user.Password.Validate(input.CurrentPassword)
 	if err != nil {
 		app.writeInternalServerError(w, r, err)
@@ -394,6 +759,19 @@ func (app *application) changeUserPassword(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	err = app.validatePassword(v, "new_password", input.NewPassword, user)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.writeErrorResponse(w, r, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	previous := *user.Password
+
 	user.Password.Plaintext = input.NewPassword
 	err = user.Password.CreateHash()
 	if err != nil {
@@ -407,6 +785,12 @@ func (app *application) changeUserPassword(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	err = app.savePreviousPassword(user.ID, previous)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
 	err = app.models.Sessions.ExpireAllSessionsByUserIDExceptOne(user.ID, *user.SessionID)
 	if err != nil {
 		app.writeInternalServerError(w, r, err)
@@ -439,17 +823,8 @@ func (app *application) getStudent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if *sessionUser.Role != data.RoleAdministrator {
-		ok, err := app.models.Users.IsUserTeacherOfStudent(student.ID, sessionUser.ID)
-		if err != nil {
-			app.writeInternalServerError(w, r, err)
-			return
-		}
-
-		if !ok {
-			app.notAllowed(w, r)
-			return
-		}
+	if !app.authorize(w, r, policy.StudentViewProfile, app.isTeacherOfStudent(sessionUser, student.ID)) {
+		return
 	}
 
 	err = app.outputJSON(w, http.StatusOK, envelope{"student": student})
@@ -511,7 +886,7 @@ func (app *application) addParentToStudent(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if *parent.Role != data.RoleParent {
+	if !parent.Roles.Has(data.RoleParent) {
 		app.writeErrorResponse(w, r, http.StatusBadRequest, data.ErrNotAParent.Error())
 		return
 	}
@@ -581,7 +956,7 @@ func (app *application) removeParentFromStudent(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	if *parent.Role != data.RoleParent {
+	if !parent.Roles.Has(data.RoleParent) {
 		app.writeErrorResponse(w, r, http.StatusBadRequest, data.ErrNotAParent.Error())
 		return
 	}
@@ -624,10 +999,21 @@ func (app *application) myInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var recoveryCodesRemaining *int
+	if *sessionUser.TotpEnabled {
+		count, err := app.models.RecoveryCodes.CountRemainingRecoveryCodes(sessionUser.ID)
+		if err != nil {
+			app.writeInternalServerError(w, r, err)
+			return
+		}
+		recoveryCodesRemaining = &count
+	}
+
 	err = app.outputJSON(w, http.StatusOK, envelope{
-		"user":         &data.User{ID: sessionUser.ID, Name: sessionUser.Name, Role: sessionUser.Role},
-		"children":     children,
-		"current_year": currentYear,
+		"user":                     &data.User{ID: sessionUser.ID, Name: sessionUser.Name, Role: sessionUser.Role, Roles: sessionUser.Roles},
+		"children":                 children,
+		"current_year":             currentYear,
+		"recovery_codes_remaining": recoveryCodesRemaining,
 	})
 	if err != nil {
 		app.writeInternalServerError(w, r, err)
@@ -648,9 +1034,7 @@ func (app *application) start2FA(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	uri := fmt.Sprintf("otpauth://totp/Lavurso:%s?secret=%s&issuer=Lavurso", *user.Email, token)
-
-	err = app.outputJSON(w, http.StatusOK, envelope{"uri": uri})
+	err = app.outputJSON(w, http.StatusOK, envelope{"uri": token.URI(app.config.TOTP.Issuer, *user.Email), "secret": token})
 	if err != nil {
 		app.writeInternalServerError(w, r, err)
 	}
@@ -683,7 +1067,7 @@ func (app *application) enable2FA(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ok, err := user.TotpSecret.Validate(*input.Code)
+	ok, err := app.validateTOTP(user.ID, user.TotpSecret, user.TotpLastStep, *input.Code)
 	if err != nil {
 		app.writeInternalServerError(w, r, err)
 		return
@@ -699,7 +1083,13 @@ func (app *application) enable2FA(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = app.outputJSON(w, http.StatusOK, envelope{"message": "success"})
+	codes, err := app.models.RecoveryCodes.NewRecoveryCodesForUser(user.ID)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.outputJSON(w, http.StatusOK, envelope{"recovery_codes": codes})
 	if err != nil {
 		app.writeInternalServerError(w, r, err)
 	}
@@ -719,8 +1109,34 @@ func (app *application) disable2FA(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = app.models.RecoveryCodes.DeleteRecoveryCodesForUser(user.ID)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
 	err = app.outputJSON(w, http.StatusOK, envelope{"message": "success"})
 	if err != nil {
 		app.writeInternalServerError(w, r, err)
 	}
 }
+
+func (app *application) regenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromContext(r)
+
+	if !*user.TotpEnabled {
+		app.writeErrorResponse(w, r, http.StatusConflict, data.Err2FANotEnabled.Error())
+		return
+	}
+
+	codes, err := app.models.RecoveryCodes.NewRecoveryCodesForUser(user.ID)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	err = app.outputJSON(w, http.StatusOK, envelope{"recovery_codes": codes})
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+	}
+}
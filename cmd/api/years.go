@@ -7,20 +7,18 @@ import (
 	"strconv"
 
 	"github.com/annusingmar/lavurso-backend/internal/data"
-	"github.com/annusingmar/lavurso-backend/internal/data/gen/lavurso/public/model"
 	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/annusingmar/lavurso-backend/internal/policy"
 	"github.com/annusingmar/lavurso-backend/internal/validator"
 	"github.com/go-chi/chi/v5"
 	"golang.org/x/exp/slices"
 )
 
 func (app *application) getAllYears(w http.ResponseWriter, r *http.Request) {
-	sessionUser := app.getUserFromContext(r)
-
 	var err error
 	var years []*data.YearExt
 
-	if *sessionUser.Role == data.RoleAdministrator && r.URL.Query().Get("stats") == "true" {
+	if app.can(r, policy.YearManage) && r.URL.Query().Get("stats") == "true" {
 		years, err = app.models.Years.ListAllYearsWithStats()
 	} else {
 		years, err = app.models.Years.ListAllYears()
@@ -58,16 +56,8 @@ func (app *application) getYearsForStudent(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if sessionUser.ID != student.ID && *sessionUser.Role != data.RoleAdministrator {
-		ok, err := app.models.Users.IsUserTeacherOrParentOfStudent(student.ID, sessionUser.ID)
-		if err != nil {
-			app.writeInternalServerError(w, r, err)
-			return
-		}
-		if !ok {
-			app.notAllowed(w, r)
-			return
-		}
+	if !app.authorize(w, r, policy.StudentView, app.isRelatedToStudent(sessionUser, student.ID)) {
+		return
 	}
 
 	years, err := app.models.Years.GetYearsForStudent(student.ID)
@@ -198,7 +188,13 @@ func (app *application) setYearsForClass(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// newYear starts a new school year, with query param 'dry_run=true'
+// it only reports which classes would be renamed, created or archived
 func (app *application) newYear(w http.ResponseWriter, r *http.Request) {
+	sessionUser := app.getUserFromContext(r)
+
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+
 	var input struct {
 		DisplayName string `json:"display_name"`
 		NewClasses  []struct {
@@ -224,6 +220,7 @@ func (app *application) newYear(w http.ResponseWriter, r *http.Request) {
 	var classIDs []int
 
 	for _, tc := range input.TransferredClasses {
+		v.Check(!slices.Contains(classIDs, tc.ClassID), "class_id", fmt.Sprintf("class id %d is transferred more than once", tc.ClassID))
 		classIDs = append(classIDs, tc.ClassID)
 		v.Check(tc.DisplayName != "", "display_name", fmt.Sprintf("class id %d name cannot be empty", tc.ClassID))
 	}
@@ -246,80 +243,81 @@ func (app *application) newYear(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var archiveIDs []int
-
-	for _, id := range allClassIDs {
-		if !slices.Contains(classIDs, id) {
-			archiveIDs = append(archiveIDs, id)
-		}
+	plan := &data.RolloverPlan{
+		DisplayName:  input.DisplayName,
+		RolledOverBy: &sessionUser.ID,
 	}
 
-	year := data.Year{
-		DisplayName: &input.DisplayName,
-		Current:     helpers.ToPtr(false),
-	}
-
-	err = app.models.Years.InsertYear(&year)
-	if err != nil {
-		app.writeInternalServerError(w, r, err)
-		return
-	}
-
-	var classYears []*model.ClassesYears
-
 	for _, nc := range input.NewClasses {
 		nc := nc
-		class := &data.Class{
-			Name: &nc.Name,
-		}
-
-		err := app.models.Classes.InsertClass(class)
-		if err != nil {
-			app.writeInternalServerError(w, r, err)
-			return
-		}
-
-		classYears = append(classYears, &model.ClassesYears{YearID: &year.ID, ClassID: &class.ID, DisplayName: &nc.DisplayName})
+		plan.NewClasses = append(plan.NewClasses, &data.ClassExt{
+			Class:       data.Class{Name: &nc.Name},
+			DisplayName: &nc.DisplayName,
+		})
 	}
 
 	for _, tc := range input.TransferredClasses {
 		tc := tc
-		classYears = append(classYears, &model.ClassesYears{
-			DisplayName: &tc.DisplayName,
+		plan.TransferredClasses = append(plan.TransferredClasses, &data.ClassYear{
 			ClassID:     &tc.ClassID,
-			YearID:      &year.ID,
+			DisplayName: &tc.DisplayName,
 		})
 	}
 
-	for _, cy := range classYears {
-		err := app.models.Years.InsertYearForClass(cy)
+	report, err := app.models.Rollovers.RollOver(plan, dryRun)
+	if err != nil {
+		app.writeInternalServerError(w, r, err)
+		return
+	}
+
+	if dryRun {
+		err = app.outputJSON(w, http.StatusOK, envelope{"report": report})
 		if err != nil {
 			app.writeInternalServerError(w, r, err)
-			return
 		}
+		return
 	}
 
-	err = app.models.Years.RemoveCurrentYear()
+	err = app.outputJSON(w, http.StatusCreated, envelope{"year": report.Year, "report": report})
 	if err != nil {
 		app.writeInternalServerError(w, r, err)
+	}
+}
+
+// getLatestRollover tells until when the latest rollover can be undone
+func (app *application) getLatestRollover(w http.ResponseWriter, r *http.Request) {
+	rollover, err := app.models.Rollovers.GetLatestRollover()
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoRollover):
+			app.writeErrorResponse(w, r, http.StatusNotFound, err.Error())
+		default:
+			app.writeInternalServerError(w, r, err)
+		}
 		return
 	}
 
-	err = app.models.Years.SetYearAsCurrent(year.ID)
+	err = app.outputJSON(w, http.StatusOK, envelope{"rollover": rollover, "undo_until": rollover.At.Add(app.config.Years.RolloverUndoWindow)})
 	if err != nil {
 		app.writeInternalServerError(w, r, err)
-		return
 	}
+}
 
-	for _, id := range archiveIDs {
-		err = app.models.Users.ArchiveUsersByClassID(id)
-		if err != nil {
+func (app *application) undoRollover(w http.ResponseWriter, r *http.Request) {
+	_, err := app.models.Rollovers.UndoRollover(app.config.Years.RolloverUndoWindow)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoRollover):
+			app.writeErrorResponse(w, r, http.StatusNotFound, err.Error())
+		case errors.Is(err, data.ErrUndoWindowPassed) || errors.Is(err, data.ErrRolloverYearInUse):
+			app.writeErrorResponse(w, r, http.StatusConflict, err.Error())
+		default:
 			app.writeInternalServerError(w, r, err)
-			return
 		}
+		return
 	}
 
-	err = app.outputJSON(w, http.StatusCreated, envelope{"year": year})
+	err = app.outputJSON(w, http.StatusOK, envelope{"message": "success"})
 	if err != nil {
 		app.writeInternalServerError(w, r, err)
 	}
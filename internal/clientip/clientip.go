@@ -0,0 +1,165 @@
+package clientip
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Resolver finds the client's IP address of a request,
+// trusting forwarding headers only when they were set by trusted proxies
+type Resolver struct {
+	TrustedProxies []netip.Prefix
+}
+
+// ParsePrefixes parses CIDRs, single addresses are taken as prefixes of their full length
+func ParsePrefixes(values []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+
+	for _, value := range values {
+		if !strings.Contains(value, "/") {
+			addr, err := netip.ParseAddr(value)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, nil
+}
+
+func (res *Resolver) trusted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range res.TrustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address the request came from. If that is a trusted proxy,
+// the forwarding chain from the Forwarded, X-Forwarded-For or X-Real-IP header
+// is walked from the right and the first address that isn't a trusted proxy is returned.
+func (res *Resolver) ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	remote, err := netip.ParseAddr(host)
+	if err != nil {
+		return host
+	}
+	remote = remote.Unmap()
+
+	if !res.trusted(remote) {
+		return remote.String()
+	}
+
+	chain := forwardedChain(r.Header)
+
+	client := remote
+	for i := len(chain) - 1; i >= 0; i-- {
+		addr, ok := parseNode(chain[i])
+		if !ok {
+			// obfuscated or unknown identifiers can't be followed further
+			break
+		}
+
+		client = addr
+		if !res.trusted(addr) {
+			break
+		}
+	}
+
+	return client.String()
+}
+
+// forwardedChain returns the forwarding chain from the first header that is set,
+// with the client first and the last proxy last
+func forwardedChain(header http.Header) []string {
+	if values := header.Values("Forwarded"); len(values) > 0 {
+		return parseForwarded(values)
+	}
+
+	if values := header.Values("X-Forwarded-For"); len(values) > 0 {
+		var chain []string
+		for _, value := range values {
+			for _, node := range strings.Split(value, ",") {
+				chain = append(chain, strings.TrimSpace(node))
+			}
+		}
+		return chain
+	}
+
+	if value := header.Get("X-Real-IP"); value != "" {
+		return []string{strings.TrimSpace(value)}
+	}
+
+	return nil
+}
+
+// parseForwarded returns the for= parameters of RFC 7239 Forwarded headers,
+// elements without one are kept as empty so they stop the chain
+func parseForwarded(values []string) []string {
+	var chain []string
+
+	for _, value := range values {
+		for _, element := range splitQuoted(value, ',') {
+			var node string
+			for _, pair := range splitQuoted(element, ';') {
+				key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(key, "for") {
+					node = strings.Trim(val, `"`)
+				}
+			}
+			chain = append(chain, node)
+		}
+	}
+
+	return chain
+}
+
+// splitQuoted splits s on sep outside of double quotes
+func splitQuoted(s string, sep rune) []string {
+	var parts []string
+
+	quoted := false
+	start := 0
+	for i, c := range s {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+// parseNode parses an address that may have a port or be in brackets,
+// like 192.0.2.43, 192.0.2.43:47011, [2001:db8::1] or [2001:db8::1]:4711
+func parseNode(node string) (netip.Addr, bool) {
+	if addrPort, err := netip.ParseAddrPort(node); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(node, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap(), true
+}
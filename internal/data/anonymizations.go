@@ -0,0 +1,170 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/annusingmar/lavurso-backend/internal/data/gen/lavurso/public/model"
+	"github.com/annusingmar/lavurso-backend/internal/data/gen/lavurso/public/table"
+	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/annusingmar/lavurso-backend/internal/types"
+	"github.com/go-jet/jet/v2/postgres"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// AnonymizedName replaces the names of anonymized users
+const AnonymizedName = "Anonymized user"
+
+var (
+	ErrUserNotArchived   = errors.New("user is not archived")
+	ErrUserAnonymized    = errors.New("user is already anonymized")
+	ErrRetentionNotEnded = errors.New("retention period of the user's data has not ended")
+)
+
+type Anonymization = model.Anonymizations
+
+type AnonymizationExt struct {
+	Anonymization
+	AnonymizedBy *User `json:"anonymized_by" alias:"anonymized_by"`
+}
+
+type AnonymizationModel struct {
+	DB *sql.DB
+}
+
+func (m AnonymizationModel) GetAnonymizations() ([]*AnonymizationExt, error) {
+	anonymizedBy := table.Users.AS("anonymized_by")
+
+	query := postgres.SELECT(table.Anonymizations.AllColumns, anonymizedBy.ID, anonymizedBy.Name, anonymizedBy.Role).
+		FROM(table.Anonymizations.
+			LEFT_JOIN(anonymizedBy, anonymizedBy.ID.EQ(table.Anonymizations.AnonymizedBy))).
+		ORDER_BY(table.Anonymizations.At.DESC())
+
+	var anonymizations []*AnonymizationExt
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &anonymizations)
+	if err != nil {
+		return nil, err
+	}
+
+	return anonymizations, nil
+}
+
+// GetAnonymizationCandidates returns archived users who haven't been anonymized yet,
+// whether their retention period has ended depends on their role
+func (m AnonymizationModel) GetAnonymizationCandidates() ([]*User, error) {
+	query := postgres.SELECT(table.Users.ID, table.Users.Role, table.Users.Archived, table.Users.ArchivedAt).
+		FROM(table.Users).
+		WHERE(postgres.AND(
+			table.Users.Archived.IS_TRUE(),
+			table.Users.ArchivedAt.IS_NOT_NULL(),
+			table.Users.ID.NOT_IN(postgres.SELECT(table.Anonymizations.UserID).FROM(table.Anonymizations)),
+		)).
+		ORDER_BY(table.Users.ArchivedAt.ASC())
+
+	var users []*User
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &users)
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// AnonymizeUser replaces the user's personal data with placeholders, detaches them
+// from parents and children, removes their sessions and credentials and the IP
+// addresses in their logs. Marks and other school records are kept.
+// anonymizedBy is nil when the retention policy anonymized the user.
+func (m AnonymizationModel) AnonymizeUser(user *User, anonymizedBy *int) error {
+	uid := helpers.PostgresInt(user.ID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// the record is inserted first, so concurrent anonymizations of the same user conflict
+	_, err = table.Anonymizations.INSERT(table.Anonymizations.UserID, table.Anonymizations.AnonymizedBy, table.Anonymizations.ArchivedAt).
+		MODEL(Anonymization{UserID: &user.ID, AnonymizedBy: anonymizedBy, ArchivedAt: user.ArchivedAt}).
+		ExecContext(ctx, tx)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return ErrUserAnonymized
+		}
+		return err
+	}
+
+	anonymized := User{
+		Name:        helpers.ToPtr(AnonymizedName),
+		Email:       helpers.ToPtr(fmt.Sprintf("anonymized-%d@invalid", user.ID)),
+		Password:    new(types.Password),
+		TotpEnabled: helpers.ToPtr(false),
+		Active:      helpers.ToPtr(false),
+	}
+	anonymized.Password.Disable()
+
+	_, err = table.Users.UPDATE(table.Users.Name, table.Users.Email, table.Users.PhoneNumber, table.Users.IDCode, table.Users.BirthDate,
+		table.Users.Password, table.Users.TotpEnabled, table.Users.TotpSecret, table.Users.TotpLastStep, table.Users.Active).
+		MODEL(anonymized).
+		WHERE(table.Users.ID.EQ(uid)).
+		ExecContext(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	// logs reference sessions, so they are detached before the sessions are removed
+	_, err = table.Logs.UPDATE(table.Logs.IP, table.Logs.SessionID).
+		SET(postgres.NULL, postgres.NULL).
+		WHERE(table.Logs.UserID.EQ(uid).
+			OR(table.Logs.SessionID.IN(postgres.SELECT(table.Sessions.ID).FROM(table.Sessions).WHERE(table.Sessions.UserID.EQ(uid))))).
+		ExecContext(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	deletes := []postgres.DeleteStatement{
+		table.ParentsChildren.DELETE().WHERE(table.ParentsChildren.ParentID.EQ(uid).OR(table.ParentsChildren.ChildID.EQ(uid))),
+		table.Sessions.DELETE().WHERE(table.Sessions.UserID.EQ(uid)),
+		table.SessionFamilies.DELETE().WHERE(table.SessionFamilies.UserID.EQ(uid)),
+		table.APITokens.DELETE().WHERE(table.APITokens.UserID.EQ(uid)),
+		table.Passkeys.DELETE().WHERE(table.Passkeys.UserID.EQ(uid)),
+		table.PasskeyChallenges.DELETE().WHERE(table.PasskeyChallenges.UserID.EQ(uid)),
+		table.RecoveryCodes.DELETE().WHERE(table.RecoveryCodes.UserID.EQ(uid)),
+		table.ExternalIdentities.DELETE().WHERE(table.ExternalIdentities.UserID.EQ(uid)),
+		table.PasswordHistory.DELETE().WHERE(table.PasswordHistory.UserID.EQ(uid)),
+		table.PasswordResets.DELETE().WHERE(table.PasswordResets.UserID.EQ(uid)),
+		table.Invitations.DELETE().WHERE(table.Invitations.UserID.EQ(uid)),
+		table.FailedLogins.DELETE().WHERE(table.FailedLogins.UserID.EQ(uid)),
+		table.Lockouts.DELETE().WHERE(table.Lockouts.UserID.EQ(uid)),
+	}
+
+	for _, stmt := range deletes {
+		_, err = stmt.ExecContext(ctx, tx)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/annusingmar/lavurso-backend/internal/data/gen/lavurso/public/model"
+	"github.com/annusingmar/lavurso-backend/internal/data/gen/lavurso/public/table"
+	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/go-jet/jet/v2/postgres"
+	"github.com/go-jet/jet/v2/qrm"
+)
+
+// APITokenPrefix is prepended to API tokens to tell them apart from session tokens
+const APITokenPrefix = "lvt_"
+
+const APITokenMaxLifetime = 365 * 24 * time.Hour
+
+var (
+	ErrNoSuchAPIToken    = errors.New("no such API token")
+	ErrInsufficientScope = errors.New("API token does not have the required scope")
+)
+
+// APITokenResources are the resources API token scopes can be given for,
+// each resource has a 'read' and a 'write' scope
+var APITokenResources = []string{
+	"users",
+	"classes",
+	"subjects",
+	"grades",
+	"groups",
+	"journals",
+	"lessons",
+	"assignments",
+	"marks",
+	"absences",
+	"messages",
+	"years",
+	"sessions",
+	"logs",
+}
+
+type APIToken = model.APITokens
+
+type APITokenModel struct {
+	DB *sql.DB
+}
+
+// ValidAPITokenScope reports whether scope is in the form resource:read or resource:write
+func ValidAPITokenScope(scope string) bool {
+	resource, action, ok := strings.Cut(scope, ":")
+	if !ok || action != "read" && action != "write" {
+		return false
+	}
+
+	for _, r := range APITokenResources {
+		if r == resource {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m APITokenModel) InsertAPIToken(t *APIToken) error {
+	stmt := table.APITokens.INSERT(table.APITokens.UserID, table.APITokens.Name, table.APITokens.Token, table.APITokens.Scopes, table.APITokens.CreatedBy, table.APITokens.Expires).
+		MODEL(t).
+		RETURNING(table.APITokens.ID)
+
+	var id []int
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := stmt.QueryContext(ctx, m.DB, &id)
+	if err != nil {
+		return err
+	}
+
+	t.ID = id[0]
+
+	return nil
+}
+
+func (m APITokenModel) GetAPITokensForUser(userID int) ([]*APIToken, error) {
+	query := postgres.SELECT(table.APITokens.AllColumns.Except(table.APITokens.Token)).
+		FROM(table.APITokens).
+		WHERE(table.APITokens.UserID.EQ(helpers.PostgresInt(userID))).
+		ORDER_BY(table.APITokens.CreatedAt.ASC())
+
+	var tokens []*APIToken
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (m APITokenModel) GetAPITokenByID(tokenID int) (*APIToken, error) {
+	query := postgres.SELECT(table.APITokens.AllColumns.Except(table.APITokens.Token)).
+		FROM(table.APITokens).
+		WHERE(table.APITokens.ID.EQ(helpers.PostgresInt(tokenID)))
+
+	var token APIToken
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &token)
+	if err != nil {
+		switch {
+		case errors.Is(err, qrm.ErrNoRows):
+			return nil, ErrNoSuchAPIToken
+		default:
+			return nil, err
+		}
+	}
+
+	return &token, nil
+}
+
+func (m APITokenModel) SetAPITokenUsed(tokenID int) error {
+	stmt := table.APITokens.UPDATE(table.APITokens.LastUsedAt).
+		SET(time.Now().UTC()).
+		WHERE(table.APITokens.ID.EQ(helpers.PostgresInt(tokenID)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := stmt.ExecContext(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m APITokenModel) DeleteAPIToken(tokenID int) error {
+	stmt := table.APITokens.DELETE().
+		WHERE(table.APITokens.ID.EQ(helpers.PostgresInt(tokenID)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := stmt.ExecContext(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// HashAPIToken hashes the token the way it is stored, without the prefix
+func HashAPIToken(plaintextToken string) []byte {
+	hash := sha256.Sum256([]byte(strings.TrimPrefix(plaintextToken, APITokenPrefix)))
+	return hash[:]
+}
@@ -0,0 +1,184 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/annusingmar/lavurso-backend/internal/data/gen/lavurso/public/model"
+	"github.com/annusingmar/lavurso-backend/internal/data/gen/lavurso/public/table"
+	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/annusingmar/lavurso-backend/internal/types"
+	"github.com/go-jet/jet/v2/postgres"
+	"github.com/go-jet/jet/v2/qrm"
+)
+
+var (
+	ErrClassNotInYear      = errors.New("class is not in the year")
+	ErrAlreadyInClass      = errors.New("student is already in the class")
+	ErrInvalidTransferDate = errors.New("transfer date is before the start of the student's current class membership")
+)
+
+// ClassMembership is a student's membership in a class during a year,
+// a missing start or end date means it lasts from the start or until the end of the year
+type ClassMembership = model.ClassMemberships
+
+type ClassMembershipExt struct {
+	ClassMembership
+	Class *ClassExt `json:"class"`
+	Year  *Year     `json:"year"`
+}
+
+type ClassMembershipModel struct {
+	DB *sql.DB
+}
+
+func (m ClassMembershipModel) GetMembershipsForStudent(studentID int) ([]*ClassMembershipExt, error) {
+	query := postgres.SELECT(table.ClassMemberships.AllColumns, table.Classes.ID, table.Classes.Name, table.ClassesYears.DisplayName, table.Years.AllColumns).
+		FROM(table.ClassMemberships.
+			INNER_JOIN(table.Classes, table.Classes.ID.EQ(table.ClassMemberships.ClassID)).
+			INNER_JOIN(table.Years, table.Years.ID.EQ(table.ClassMemberships.YearID)).
+			LEFT_JOIN(table.ClassesYears, table.ClassesYears.ClassID.EQ(table.ClassMemberships.ClassID).
+				AND(table.ClassesYears.YearID.EQ(table.ClassMemberships.YearID)))).
+		WHERE(table.ClassMemberships.StudentID.EQ(helpers.PostgresInt(studentID))).
+		ORDER_BY(table.Years.ID.ASC(), table.ClassMemberships.StartDate.ASC())
+
+	var memberships []*ClassMembershipExt
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &memberships)
+	if err != nil {
+		return nil, err
+	}
+
+	return memberships, nil
+}
+
+// TransferStudent ends the student's open membership in the year on date and starts one in the class.
+// A transfer on the day the open membership started corrects its class instead.
+// If the year is current, the student's class is changed too.
+func (m ClassMembershipModel) TransferStudent(studentID, classID int, year *Year, date *types.Date) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = transferStudent(ctx, tx, studentID, classID, year, date)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// transferStudent does TransferStudent in the transaction
+func transferStudent(ctx context.Context, tx *sql.Tx, studentID, classID int, year *Year, date *types.Date) error {
+	var count []int
+
+	err := postgres.SELECT(postgres.COUNT(table.ClassesYears.ClassID)).
+		FROM(table.ClassesYears).
+		WHERE(table.ClassesYears.ClassID.EQ(helpers.PostgresInt(classID)).
+			AND(table.ClassesYears.YearID.EQ(helpers.PostgresInt(year.ID)))).
+		QueryContext(ctx, tx, &count)
+	if err != nil {
+		return err
+	}
+
+	if count[0] == 0 {
+		return ErrClassNotInYear
+	}
+
+	var current ClassMembership
+
+	err = postgres.SELECT(table.ClassMemberships.AllColumns).
+		FROM(table.ClassMemberships).
+		WHERE(table.ClassMemberships.StudentID.EQ(helpers.PostgresInt(studentID)).
+			AND(table.ClassMemberships.YearID.EQ(helpers.PostgresInt(year.ID))).
+			AND(table.ClassMemberships.EndDate.IS_NULL())).
+		FOR(postgres.UPDATE()).
+		QueryContext(ctx, tx, &current)
+	if err != nil && !errors.Is(err, qrm.ErrNoRows) {
+		return err
+	}
+
+	start := true
+
+	if current.ID != 0 {
+		if *current.ClassID == classID {
+			return ErrAlreadyInClass
+		}
+
+		switch {
+		case current.StartDate == nil || current.StartDate.String() < date.String():
+			_, err = table.ClassMemberships.UPDATE(table.ClassMemberships.EndDate).
+				SET(postgres.DateT(*date.Time)).
+				WHERE(table.ClassMemberships.ID.EQ(helpers.PostgresInt(current.ID))).
+				ExecContext(ctx, tx)
+		case current.StartDate.String() == date.String():
+			_, err = table.ClassMemberships.UPDATE(table.ClassMemberships.ClassID).
+				SET(helpers.PostgresInt(classID)).
+				WHERE(table.ClassMemberships.ID.EQ(helpers.PostgresInt(current.ID))).
+				ExecContext(ctx, tx)
+			start = false
+		default:
+			return ErrInvalidTransferDate
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if start {
+		_, err = table.ClassMemberships.INSERT(table.ClassMemberships.StudentID, table.ClassMemberships.ClassID, table.ClassMemberships.YearID, table.ClassMemberships.StartDate).
+			MODEL(ClassMembership{
+				StudentID: &studentID,
+				ClassID:   &classID,
+				YearID:    &year.ID,
+				StartDate: date,
+			}).
+			ExecContext(ctx, tx)
+		if err != nil {
+			return err
+		}
+	}
+
+	if *year.Current {
+		_, err = table.Users.UPDATE(table.Users.ClassID).
+			SET(helpers.PostgresInt(classID)).
+			WHERE(table.Users.ID.EQ(helpers.PostgresInt(studentID))).
+			ExecContext(ctx, tx)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// insertCurrentMembership starts the student's membership in the class in the current year,
+// nothing is inserted if no year is current
+func insertCurrentMembership(ctx context.Context, db qrm.Executable, studentID, classID int) error {
+	stmt := table.ClassMemberships.INSERT(table.ClassMemberships.StudentID, table.ClassMemberships.ClassID, table.ClassMemberships.YearID, table.ClassMemberships.StartDate).
+		QUERY(postgres.SELECT(helpers.PostgresInt(studentID), helpers.PostgresInt(classID), table.Years.ID, postgres.CURRENT_DATE()).
+			FROM(table.Years).
+			WHERE(table.Years.Current.IS_TRUE()))
+
+	_, err := stmt.ExecContext(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -233,3 +233,25 @@ func (m ClassModel) GetUsersForClassID(classID int) ([]*UserExt, error) {
 
 	return users, nil
 }
+
+// GetStudentsInClassForYear returns the students who were in the class at any time during the year
+func (m ClassModel) GetStudentsInClassForYear(classID, yearID int) ([]*UserExt, error) {
+	query := postgres.SELECT(table.Users.ID, table.Users.Name, table.Users.Role).DISTINCT().
+		FROM(table.Users.
+			INNER_JOIN(table.ClassMemberships, table.ClassMemberships.StudentID.EQ(table.Users.ID))).
+		WHERE(table.ClassMemberships.ClassID.EQ(helpers.PostgresInt(classID)).
+			AND(table.ClassMemberships.YearID.EQ(helpers.PostgresInt(yearID)))).
+		ORDER_BY(table.Users.Name.ASC())
+
+	var users []*UserExt
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &users)
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
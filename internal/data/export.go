@@ -0,0 +1,285 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"errors"
+	"time"
+
+	"github.com/annusingmar/lavurso-backend/internal/data/gen/lavurso/public/table"
+	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/annusingmar/lavurso-backend/internal/types"
+	"github.com/go-jet/jet/v2/postgres"
+	"github.com/go-jet/jet/v2/qrm"
+)
+
+// ExportVersion is increased whenever the structure of UserExport changes
+// in a way that isn't only adding fields
+const ExportVersion = 1
+
+// ExportDocumentation describes the structure of UserExport
+//
+//go:embed export.md
+var ExportDocumentation []byte
+
+// UserExport holds all data about a user, it is documented in export.md
+type UserExport struct {
+	Version         int              `json:"version"`
+	ExportedAt      time.Time        `json:"exported_at"`
+	Profile         *User            `json:"profile"`
+	Parents         []*User          `json:"parents"`
+	Children        []*User          `json:"children"`
+	Classes         []*ExportClass   `json:"classes"`
+	Journals        []*ExportJournal `json:"journals"`
+	Lessons         []*ExportLesson  `json:"lessons"`
+	Marks           []*ExportMark    `json:"marks"`
+	Excuses         []*Excuse        `json:"excuses"`
+	DoneAssignments []*Assignment    `json:"done_assignments"`
+	Threads         []*Thread        `json:"threads"`
+	Messages        []*Message       `json:"messages"`
+	Sessions        []*Session       `json:"sessions"`
+	Logs            []*Log           `json:"logs"`
+}
+
+type ExportClass struct {
+	Year        *Year       `json:"year"`
+	Class       *Class      `json:"class"`
+	DisplayName *string     `json:"display_name" alias:"classes_years.display_name"`
+	StartDate   *types.Date `json:"start_date" alias:"class_memberships.start_date"`
+	EndDate     *types.Date `json:"end_date" alias:"class_memberships.end_date"`
+}
+
+type ExportJournal struct {
+	Journal
+	Subject *Subject `json:"subject"`
+	Year    *Year    `json:"year"`
+	Teacher bool     `json:"teacher" alias:"exportjournal.teacher"`
+}
+
+type ExportLesson struct {
+	Lesson
+	Attended bool `json:"attended" alias:"exportlesson.attended"`
+}
+
+type ExportMark struct {
+	Mark
+	Grade *Grade `json:"grade"`
+}
+
+type ExportModel struct {
+	DB *sql.DB
+}
+
+// ExportUser collects the user's data in one read-only transaction,
+// so the sections are consistent with each other
+func (m ExportModel) ExportUser(userID int) (*UserExport, error) {
+	uid := helpers.PostgresInt(userID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	export := &UserExport{
+		Version:         ExportVersion,
+		ExportedAt:      time.Now().UTC(),
+		Profile:         new(User),
+		Parents:         []*User{},
+		Children:        []*User{},
+		Classes:         []*ExportClass{},
+		Journals:        []*ExportJournal{},
+		Lessons:         []*ExportLesson{},
+		Marks:           []*ExportMark{},
+		Excuses:         []*Excuse{},
+		DoneAssignments: []*Assignment{},
+		Threads:         []*Thread{},
+		Messages:        []*Message{},
+		Sessions:        []*Session{},
+		Logs:            []*Log{},
+	}
+
+	userColumns := table.Users.AllColumns.Except(table.Users.Password, table.Users.TotpSecret)
+
+	err = postgres.SELECT(userColumns).
+		FROM(table.Users).
+		WHERE(table.Users.ID.EQ(uid)).
+		QueryContext(ctx, tx, export.Profile)
+	if err != nil {
+		switch {
+		case errors.Is(err, qrm.ErrNoRows):
+			return nil, ErrNoSuchUser
+		default:
+			return nil, err
+		}
+	}
+
+	err = postgres.SELECT(userColumns).
+		FROM(table.Users.
+			INNER_JOIN(table.ParentsChildren, table.ParentsChildren.ParentID.EQ(table.Users.ID))).
+		WHERE(table.ParentsChildren.ChildID.EQ(uid)).
+		ORDER_BY(table.Users.Name.ASC()).
+		QueryContext(ctx, tx, &export.Parents)
+	if err != nil {
+		return nil, err
+	}
+
+	err = postgres.SELECT(userColumns).
+		FROM(table.Users.
+			INNER_JOIN(table.ParentsChildren, table.ParentsChildren.ChildID.EQ(table.Users.ID))).
+		WHERE(table.ParentsChildren.ParentID.EQ(uid)).
+		ORDER_BY(table.Users.Name.ASC()).
+		QueryContext(ctx, tx, &export.Children)
+	if err != nil {
+		return nil, err
+	}
+
+	err = postgres.SELECT(table.Years.AllColumns, table.Classes.AllColumns, table.ClassesYears.DisplayName,
+		table.ClassMemberships.StartDate, table.ClassMemberships.EndDate).
+		FROM(table.ClassMemberships.
+			INNER_JOIN(table.Classes, table.Classes.ID.EQ(table.ClassMemberships.ClassID)).
+			INNER_JOIN(table.Years, table.Years.ID.EQ(table.ClassMemberships.YearID)).
+			LEFT_JOIN(table.ClassesYears, table.ClassesYears.ClassID.EQ(table.ClassMemberships.ClassID).
+				AND(table.ClassesYears.YearID.EQ(table.ClassMemberships.YearID)))).
+		WHERE(table.ClassMemberships.StudentID.EQ(uid)).
+		ORDER_BY(table.Years.ID.ASC(), table.ClassMemberships.StartDate.ASC()).
+		QueryContext(ctx, tx, &export.Classes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = postgres.SELECT(table.Journals.AllColumns, table.Subjects.AllColumns, table.Years.AllColumns,
+		postgres.Bool(false).AS("exportjournal.teacher")).
+		FROM(table.Journals.
+			INNER_JOIN(table.StudentsJournals, table.StudentsJournals.JournalID.EQ(table.Journals.ID)).
+			LEFT_JOIN(table.Subjects, table.Subjects.ID.EQ(table.Journals.SubjectID)).
+			LEFT_JOIN(table.Years, table.Years.ID.EQ(table.Journals.YearID))).
+		WHERE(table.StudentsJournals.StudentID.EQ(uid)).
+		UNION_ALL(
+			postgres.SELECT(table.Journals.AllColumns, table.Subjects.AllColumns, table.Years.AllColumns,
+				postgres.Bool(true).AS("exportjournal.teacher")).
+				FROM(table.Journals.
+					INNER_JOIN(table.TeachersJournals, table.TeachersJournals.JournalID.EQ(table.Journals.ID)).
+					LEFT_JOIN(table.Subjects, table.Subjects.ID.EQ(table.Journals.SubjectID)).
+					LEFT_JOIN(table.Years, table.Years.ID.EQ(table.Journals.YearID))).
+				WHERE(table.TeachersJournals.TeacherID.EQ(uid)),
+		).
+		ORDER_BY(postgres.IntegerColumn("journals.id").ASC()).
+		QueryContext(ctx, tx, &export.Journals)
+	if err != nil {
+		return nil, err
+	}
+
+	absent := table.Marks.AS("absent_marks")
+
+	err = postgres.SELECT(table.Lessons.AllColumns,
+		postgres.NOT(postgres.EXISTS(
+			postgres.SELECT(postgres.Int(1)).
+				FROM(absent).
+				WHERE(postgres.AND(
+					absent.LessonID.EQ(table.Lessons.ID),
+					absent.UserID.EQ(uid),
+					absent.Type.EQ(postgres.String(MarkAbsent)),
+				)),
+		)).AS("exportlesson.attended")).
+		FROM(table.Lessons.
+			INNER_JOIN(table.StudentsJournals, table.StudentsJournals.JournalID.EQ(table.Lessons.JournalID))).
+		WHERE(table.StudentsJournals.StudentID.EQ(uid)).
+		ORDER_BY(table.Lessons.Date.ASC(), table.Lessons.ID.ASC()).
+		QueryContext(ctx, tx, &export.Lessons)
+	if err != nil {
+		return nil, err
+	}
+
+	err = postgres.SELECT(table.Marks.AllColumns, table.Grades.AllColumns).
+		FROM(table.Marks.
+			LEFT_JOIN(table.Grades, table.Grades.ID.EQ(table.Marks.GradeID))).
+		WHERE(table.Marks.UserID.EQ(uid)).
+		ORDER_BY(table.Marks.CreatedAt.ASC()).
+		QueryContext(ctx, tx, &export.Marks)
+	if err != nil {
+		return nil, err
+	}
+
+	// excuses for the user's absences and excuses written by the user
+	err = postgres.SELECT(table.Excuses.AllColumns).
+		FROM(table.Excuses.
+			INNER_JOIN(table.Marks, table.Marks.ID.EQ(table.Excuses.MarkID))).
+		WHERE(table.Marks.UserID.EQ(uid).OR(table.Excuses.UserID.EQ(uid))).
+		ORDER_BY(table.Excuses.At.ASC()).
+		QueryContext(ctx, tx, &export.Excuses)
+	if err != nil {
+		return nil, err
+	}
+
+	err = postgres.SELECT(table.Assignments.AllColumns).
+		FROM(table.Assignments.
+			INNER_JOIN(table.DoneAssignments, table.DoneAssignments.AssignmentID.EQ(table.Assignments.ID))).
+		WHERE(table.DoneAssignments.UserID.EQ(uid)).
+		ORDER_BY(table.Assignments.ID.ASC()).
+		QueryContext(ctx, tx, &export.DoneAssignments)
+	if err != nil {
+		return nil, err
+	}
+
+	// threads the user created, is a member of directly or through a group, or wrote in
+	err = postgres.SELECT(table.Threads.AllColumns).
+		FROM(table.Threads).
+		WHERE(postgres.OR(
+			table.Threads.UserID.EQ(uid),
+			table.Threads.ID.IN(
+				postgres.SELECT(table.ThreadsRecipients.ThreadID).
+					FROM(table.ThreadsRecipients.
+						LEFT_JOIN(table.UsersGroups, table.UsersGroups.GroupID.EQ(table.ThreadsRecipients.GroupID))).
+					WHERE(table.ThreadsRecipients.UserID.EQ(uid).OR(table.UsersGroups.UserID.EQ(uid))),
+			),
+			table.Threads.ID.IN(
+				postgres.SELECT(table.Messages.ThreadID).
+					FROM(table.Messages).
+					WHERE(table.Messages.UserID.EQ(uid)),
+			),
+		)).
+		ORDER_BY(table.Threads.CreatedAt.ASC()).
+		QueryContext(ctx, tx, &export.Threads)
+	if err != nil {
+		return nil, err
+	}
+
+	err = postgres.SELECT(table.Messages.AllColumns).
+		FROM(table.Messages).
+		WHERE(table.Messages.UserID.EQ(uid)).
+		ORDER_BY(table.Messages.CreatedAt.ASC()).
+		QueryContext(ctx, tx, &export.Messages)
+	if err != nil {
+		return nil, err
+	}
+
+	err = postgres.SELECT(table.Sessions.AllColumns.Except(table.Sessions.Token)).
+		FROM(table.Sessions).
+		WHERE(table.Sessions.UserID.EQ(uid)).
+		ORDER_BY(table.Sessions.LoggedIn.ASC()).
+		QueryContext(ctx, tx, &export.Sessions)
+	if err != nil {
+		return nil, err
+	}
+
+	err = postgres.SELECT(table.Logs.AllColumns).
+		FROM(table.Logs).
+		WHERE(table.Logs.UserID.EQ(uid)).
+		ORDER_BY(table.Logs.At.ASC()).
+		QueryContext(ctx, tx, &export.Logs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
@@ -0,0 +1,204 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/annusingmar/lavurso-backend/internal/data/gen/lavurso/public/model"
+	"github.com/annusingmar/lavurso-backend/internal/data/gen/lavurso/public/table"
+	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/go-jet/jet/v2/postgres"
+	"github.com/go-jet/jet/v2/qrm"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrNoSuchExternalIdentity    = errors.New("no such external identity")
+	ErrExternalIdentityLinked    = errors.New("external identity already linked to a user")
+	ErrInvalidOIDCState          = errors.New("invalid or expired login state")
+	ErrNoUserForExternalIdentity = errors.New("no user is linked to this external identity")
+)
+
+type ExternalIdentity = model.ExternalIdentities
+
+type OIDCState = model.OidcStates
+
+type ExternalIdentityModel struct {
+	DB *sql.DB
+}
+
+func (m ExternalIdentityModel) InsertExternalIdentity(ei *ExternalIdentity) error {
+	stmt := table.ExternalIdentities.INSERT(table.ExternalIdentities.UserID, table.ExternalIdentities.Provider, table.ExternalIdentities.Subject, table.ExternalIdentities.Email).
+		MODEL(ei).
+		RETURNING(table.ExternalIdentities.ID)
+
+	var id []int
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := stmt.QueryContext(ctx, m.DB, &id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return ErrExternalIdentityLinked
+		}
+		return err
+	}
+
+	ei.ID = id[0]
+
+	return nil
+}
+
+func (m ExternalIdentityModel) GetExternalIdentitiesForUser(userID int) ([]*ExternalIdentity, error) {
+	query := postgres.SELECT(table.ExternalIdentities.AllColumns).
+		FROM(table.ExternalIdentities).
+		WHERE(table.ExternalIdentities.UserID.EQ(helpers.PostgresInt(userID))).
+		ORDER_BY(table.ExternalIdentities.CreatedAt.ASC())
+
+	var identities []*ExternalIdentity
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &identities)
+	if err != nil {
+		return nil, err
+	}
+
+	return identities, nil
+}
+
+func (m ExternalIdentityModel) GetExternalIdentityByID(identityID int) (*ExternalIdentity, error) {
+	query := postgres.SELECT(table.ExternalIdentities.AllColumns).
+		FROM(table.ExternalIdentities).
+		WHERE(table.ExternalIdentities.ID.EQ(helpers.PostgresInt(identityID)))
+
+	var identity ExternalIdentity
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &identity)
+	if err != nil {
+		switch {
+		case errors.Is(err, qrm.ErrNoRows):
+			return nil, ErrNoSuchExternalIdentity
+		default:
+			return nil, err
+		}
+	}
+
+	return &identity, nil
+}
+
+func (m ExternalIdentityModel) GetExternalIdentity(provider, subject string) (*ExternalIdentity, error) {
+	query := postgres.SELECT(table.ExternalIdentities.AllColumns).
+		FROM(table.ExternalIdentities).
+		WHERE(table.ExternalIdentities.Provider.EQ(postgres.String(provider)).
+			AND(table.ExternalIdentities.Subject.EQ(postgres.String(subject))))
+
+	var identity ExternalIdentity
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &identity)
+	if err != nil {
+		switch {
+		case errors.Is(err, qrm.ErrNoRows):
+			return nil, ErrNoSuchExternalIdentity
+		default:
+			return nil, err
+		}
+	}
+
+	return &identity, nil
+}
+
+func (m ExternalIdentityModel) SetExternalIdentityLoggedIn(identityID int, email *string) error {
+	stmt := table.ExternalIdentities.UPDATE(table.ExternalIdentities.Email, table.ExternalIdentities.LastLoginAt).
+		SET(email, time.Now().UTC()).
+		WHERE(table.ExternalIdentities.ID.EQ(helpers.PostgresInt(identityID)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := stmt.ExecContext(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m ExternalIdentityModel) DeleteExternalIdentity(identityID int) error {
+	stmt := table.ExternalIdentities.DELETE().
+		WHERE(table.ExternalIdentities.ID.EQ(helpers.PostgresInt(identityID)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := stmt.ExecContext(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m ExternalIdentityModel) InsertOIDCState(s *OIDCState) error {
+	deleteStmt := table.OidcStates.DELETE().
+		WHERE(table.OidcStates.Expires.LT(postgres.TimestampzT(time.Now().UTC())))
+
+	insertStmt := table.OidcStates.INSERT(table.OidcStates.MutableColumns).
+		MODEL(s)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := deleteStmt.ExecContext(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+
+	_, err = insertStmt.ExecContext(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UseOIDCState deletes the unexpired state issued for the provider and returns it,
+// so every login attempt can only be finished once
+func (m ExternalIdentityModel) UseOIDCState(state, provider string) (*OIDCState, error) {
+	stmt := table.OidcStates.DELETE().
+		WHERE(postgres.AND(
+			table.OidcStates.State.EQ(postgres.String(state)),
+			table.OidcStates.Provider.EQ(postgres.String(provider)),
+			table.OidcStates.Expires.GT(postgres.TimestampzT(time.Now().UTC())),
+		)).
+		RETURNING(table.OidcStates.AllColumns)
+
+	var s OIDCState
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := stmt.QueryContext(ctx, m.DB, &s)
+	if err != nil {
+		switch {
+		case errors.Is(err, qrm.ErrNoRows):
+			return nil, ErrInvalidOIDCState
+		default:
+			return nil, err
+		}
+	}
+
+	return &s, nil
+}
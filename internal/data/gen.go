@@ -30,25 +30,47 @@ func main() {
 							UseField(func(columnMetaData metadata.Column) template.TableModelField {
 								defaultTableModelField := template.DefaultTableModelField(columnMetaData)
 
-								if table.Name == "users" && columnMetaData.Name == "password" {
+								if table.Name == "users" && columnMetaData.Name == "password" ||
+									table.Name == "password_history" && columnMetaData.Name == "password" {
 									defaultTableModelField.Tags = append(defaultTableModelField.Tags, `json:"-"`)
 									defaultTableModelField.Type = template.NewType(new(types.Password))
 								} else if table.Name == "sessions" && columnMetaData.Name == "token" {
 									defaultTableModelField.Tags = append(defaultTableModelField.Tags, `json:"token"`)
 									defaultTableModelField.Type = template.NewType(new(types.Token))
+								} else if table.Name == "refresh_tokens" && columnMetaData.Name == "token" {
+									defaultTableModelField.Tags = append(defaultTableModelField.Tags, `json:"token"`)
+									defaultTableModelField.Type = template.NewType(new(types.Token))
+								} else if table.Name == "api_tokens" && columnMetaData.Name == "token" {
+									defaultTableModelField.Tags = append(defaultTableModelField.Tags, `json:"-"`)
+									defaultTableModelField.Type = template.NewType(new(types.Token))
+								} else if table.Name == "password_resets" && columnMetaData.Name == "token" ||
+									table.Name == "invitations" && columnMetaData.Name == "token" {
+									defaultTableModelField.Tags = append(defaultTableModelField.Tags, `json:"-"`)
+									defaultTableModelField.Type = template.NewType(new(types.Token))
 								} else if table.Name == "users" && columnMetaData.Name == "totp_secret" {
 									defaultTableModelField.Tags = append(defaultTableModelField.Tags, `json:"-"`)
 									defaultTableModelField.Type = template.NewType(new(types.TOTPSecret))
+								} else if table.Name == "users" && columnMetaData.Name == "totp_last_step" {
+									defaultTableModelField.Tags = append(defaultTableModelField.Tags, `json:"-"`)
 								} else {
 									defaultTableModelField.Tags = append(defaultTableModelField.Tags, fmt.Sprintf(`json:"%s,omitempty"`, columnMetaData.Name))
 								}
 
 								if table.Name == "assignments" && columnMetaData.Name == "deadline" ||
 									table.Name == "users" && columnMetaData.Name == "birth_date" ||
-									table.Name == "lessons" && columnMetaData.Name == "date" {
+									table.Name == "lessons" && columnMetaData.Name == "date" ||
+									table.Name == "class_memberships" && (columnMetaData.Name == "start_date" || columnMetaData.Name == "end_date") {
 									defaultTableModelField.Type = template.NewType(new(types.Date))
 								}
 
+								if table.Name == "api_tokens" && columnMetaData.Name == "scopes" {
+									defaultTableModelField.Type = template.NewType(new(types.Scopes))
+								}
+
+								if table.Name == "users" && columnMetaData.Name == "roles" {
+									defaultTableModelField.Type = template.NewType(new(types.Roles))
+								}
+
 								switch defaultTableModelField.Type.Name {
 								case "int32", "*int32":
 									if columnMetaData.Name != "id" {
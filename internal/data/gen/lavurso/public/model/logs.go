@@ -12,13 +12,16 @@ import (
 )
 
 type Logs struct {
-	UserID       *int       `json:"user_id,omitempty"`
-	SessionID    *int       `json:"session_id,omitempty"`
-	Method       *string    `json:"method,omitempty"`
-	Target       *string    `json:"target,omitempty"`
-	IP           *string    `json:"ip,omitempty"`
-	ResponseCode *int       `json:"response_code,omitempty"`
-	Duration     *int       `json:"duration,omitempty"`
-	At           *time.Time `json:"at,omitempty"`
-	ID           int64      `sql:"primary_key" json:"id,omitempty"`
+	UserID         *int       `json:"user_id,omitempty"`
+	SessionID      *int       `json:"session_id,omitempty"`
+	Method         *string    `json:"method,omitempty"`
+	Target         *string    `json:"target,omitempty"`
+	IP             *string    `json:"ip,omitempty"`
+	ResponseCode   *int       `json:"response_code,omitempty"`
+	Duration       *int       `json:"duration,omitempty"`
+	At             *time.Time `json:"at,omitempty"`
+	ID             int64      `sql:"primary_key" json:"id,omitempty"`
+	Event          *string    `json:"event,omitempty"`
+	APITokenID     *int       `json:"api_token_id,omitempty"`
+	ImpersonatorID *int       `json:"impersonator_id,omitempty"`
 }
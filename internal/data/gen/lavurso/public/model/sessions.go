@@ -13,12 +13,15 @@ import (
 )
 
 type Sessions struct {
-	ID           int          `sql:"primary_key" json:"id,omitempty"`
-	Token        *types.Token `json:"token"`
-	UserID       *int         `json:"user_id,omitempty"`
-	Expires      *time.Time   `json:"expires,omitempty"`
-	LoginIP      *string      `json:"login_ip,omitempty"`
-	LoginBrowser *string      `json:"login_browser,omitempty"`
-	LoggedIn     *time.Time   `json:"logged_in,omitempty"`
-	LastSeen     *time.Time   `json:"last_seen,omitempty"`
+	ID             int          `sql:"primary_key" json:"id,omitempty"`
+	Token          *types.Token `json:"token"`
+	UserID         *int         `json:"user_id,omitempty"`
+	Expires        *time.Time   `json:"expires,omitempty"`
+	LoginIP        *string      `json:"login_ip,omitempty"`
+	LoginBrowser   *string      `json:"login_browser,omitempty"`
+	LoggedIn       *time.Time   `json:"logged_in,omitempty"`
+	LastSeen       *time.Time   `json:"last_seen,omitempty"`
+	FamilyID       *int         `json:"family_id,omitempty"`
+	ImpersonatorID *int         `json:"impersonator_id,omitempty"`
+	NewDevice      *bool        `json:"new_device,omitempty"`
 }
@@ -13,18 +13,23 @@ import (
 )
 
 type Users struct {
-	ID          int               `sql:"primary_key" json:"id,omitempty"`
-	Name        *string           `json:"name,omitempty"`
-	Email       *string           `json:"email,omitempty"`
-	PhoneNumber *string           `json:"phone_number,omitempty"`
-	IDCode      *int64            `json:"id_code,omitempty"`
-	BirthDate   *types.Date       `json:"birth_date,omitempty"`
-	Password    *types.Password   `json:"-"`
-	Role        *string           `json:"role,omitempty"`
-	ClassID     *int              `json:"class_id,omitempty"`
-	CreatedAt   *time.Time        `json:"created_at,omitempty"`
-	Active      *bool             `json:"active,omitempty"`
-	Archived    *bool             `json:"archived,omitempty"`
-	TotpEnabled *bool             `json:"totp_enabled,omitempty"`
-	TotpSecret  *types.TOTPSecret `json:"-"`
+	ID             int               `sql:"primary_key" json:"id,omitempty"`
+	Name           *string           `json:"name,omitempty"`
+	Email          *string           `json:"email,omitempty"`
+	PhoneNumber    *string           `json:"phone_number,omitempty"`
+	IDCode         *int64            `json:"id_code,omitempty"`
+	BirthDate      *types.Date       `json:"birth_date,omitempty"`
+	Password       *types.Password   `json:"-"`
+	Role           *string           `json:"role,omitempty"`
+	ClassID        *int              `json:"class_id,omitempty"`
+	CreatedAt      *time.Time        `json:"created_at,omitempty"`
+	Active         *bool             `json:"active,omitempty"`
+	Archived       *bool             `json:"archived,omitempty"`
+	TotpEnabled    *bool             `json:"totp_enabled,omitempty"`
+	TotpSecret     *types.TOTPSecret `json:"-"`
+	TotpLastStep   *int64            `json:"-"`
+	ExternalAuth   *bool             `json:"external_auth,omitempty"`
+	ServiceAccount *bool             `json:"service_account,omitempty"`
+	ArchivedAt     *time.Time        `json:"archived_at,omitempty"`
+	Roles          *types.Roles      `json:"roles,omitempty"`
 }
@@ -17,15 +17,18 @@ type logsTable struct {
 	postgres.Table
 
 	//Columns
-	UserID       postgres.ColumnInteger
-	SessionID    postgres.ColumnInteger
-	Method       postgres.ColumnString
-	Target       postgres.ColumnString
-	IP           postgres.ColumnString
-	ResponseCode postgres.ColumnInteger
-	Duration     postgres.ColumnInteger
-	At           postgres.ColumnTimestampz
-	ID           postgres.ColumnInteger
+	UserID         postgres.ColumnInteger
+	SessionID      postgres.ColumnInteger
+	Method         postgres.ColumnString
+	Target         postgres.ColumnString
+	IP             postgres.ColumnString
+	ResponseCode   postgres.ColumnInteger
+	Duration       postgres.ColumnInteger
+	At             postgres.ColumnTimestampz
+	ID             postgres.ColumnInteger
+	Event          postgres.ColumnString
+	APITokenID     postgres.ColumnInteger
+	ImpersonatorID postgres.ColumnInteger
 
 	AllColumns     postgres.ColumnList
 	MutableColumns postgres.ColumnList
@@ -66,32 +69,38 @@ func newLogsTable(schemaName, tableName, alias string) *LogsTable {
 
 func newLogsTableImpl(schemaName, tableName, alias string) logsTable {
 	var (
-		UserIDColumn       = postgres.IntegerColumn("user_id")
-		SessionIDColumn    = postgres.IntegerColumn("session_id")
-		MethodColumn       = postgres.StringColumn("method")
-		TargetColumn       = postgres.StringColumn("target")
-		IPColumn           = postgres.StringColumn("ip")
-		ResponseCodeColumn = postgres.IntegerColumn("response_code")
-		DurationColumn     = postgres.IntegerColumn("duration")
-		AtColumn           = postgres.TimestampzColumn("at")
-		IDColumn           = postgres.IntegerColumn("id")
-		allColumns         = postgres.ColumnList{UserIDColumn, SessionIDColumn, MethodColumn, TargetColumn, IPColumn, ResponseCodeColumn, DurationColumn, AtColumn, IDColumn}
-		mutableColumns     = postgres.ColumnList{UserIDColumn, SessionIDColumn, MethodColumn, TargetColumn, IPColumn, ResponseCodeColumn, DurationColumn, AtColumn}
+		UserIDColumn         = postgres.IntegerColumn("user_id")
+		SessionIDColumn      = postgres.IntegerColumn("session_id")
+		MethodColumn         = postgres.StringColumn("method")
+		TargetColumn         = postgres.StringColumn("target")
+		IPColumn             = postgres.StringColumn("ip")
+		ResponseCodeColumn   = postgres.IntegerColumn("response_code")
+		DurationColumn       = postgres.IntegerColumn("duration")
+		AtColumn             = postgres.TimestampzColumn("at")
+		IDColumn             = postgres.IntegerColumn("id")
+		EventColumn          = postgres.StringColumn("event")
+		APITokenIDColumn     = postgres.IntegerColumn("api_token_id")
+		ImpersonatorIDColumn = postgres.IntegerColumn("impersonator_id")
+		allColumns           = postgres.ColumnList{UserIDColumn, SessionIDColumn, MethodColumn, TargetColumn, IPColumn, ResponseCodeColumn, DurationColumn, AtColumn, IDColumn, EventColumn, APITokenIDColumn, ImpersonatorIDColumn}
+		mutableColumns       = postgres.ColumnList{UserIDColumn, SessionIDColumn, MethodColumn, TargetColumn, IPColumn, ResponseCodeColumn, DurationColumn, AtColumn, EventColumn, APITokenIDColumn, ImpersonatorIDColumn}
 	)
 
 	return logsTable{
 		Table: postgres.NewTable(schemaName, tableName, alias, allColumns...),
 
 		//Columns
-		UserID:       UserIDColumn,
-		SessionID:    SessionIDColumn,
-		Method:       MethodColumn,
-		Target:       TargetColumn,
-		IP:           IPColumn,
-		ResponseCode: ResponseCodeColumn,
-		Duration:     DurationColumn,
-		At:           AtColumn,
-		ID:           IDColumn,
+		UserID:         UserIDColumn,
+		SessionID:      SessionIDColumn,
+		Method:         MethodColumn,
+		Target:         TargetColumn,
+		IP:             IPColumn,
+		ResponseCode:   ResponseCodeColumn,
+		Duration:       DurationColumn,
+		At:             AtColumn,
+		ID:             IDColumn,
+		Event:          EventColumn,
+		APITokenID:     APITokenIDColumn,
+		ImpersonatorID: ImpersonatorIDColumn,
 
 		AllColumns:     allColumns,
 		MutableColumns: mutableColumns,
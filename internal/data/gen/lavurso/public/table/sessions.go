@@ -17,14 +17,17 @@ type sessionsTable struct {
 	postgres.Table
 
 	//Columns
-	ID           postgres.ColumnInteger
-	Token        postgres.ColumnString
-	UserID       postgres.ColumnInteger
-	Expires      postgres.ColumnTimestampz
-	LoginIP      postgres.ColumnString
-	LoginBrowser postgres.ColumnString
-	LoggedIn     postgres.ColumnTimestampz
-	LastSeen     postgres.ColumnTimestampz
+	ID             postgres.ColumnInteger
+	Token          postgres.ColumnString
+	UserID         postgres.ColumnInteger
+	Expires        postgres.ColumnTimestampz
+	LoginIP        postgres.ColumnString
+	LoginBrowser   postgres.ColumnString
+	LoggedIn       postgres.ColumnTimestampz
+	LastSeen       postgres.ColumnTimestampz
+	FamilyID       postgres.ColumnInteger
+	ImpersonatorID postgres.ColumnInteger
+	NewDevice      postgres.ColumnBool
 
 	AllColumns     postgres.ColumnList
 	MutableColumns postgres.ColumnList
@@ -65,30 +68,36 @@ func newSessionsTable(schemaName, tableName, alias string) *SessionsTable {
 
 func newSessionsTableImpl(schemaName, tableName, alias string) sessionsTable {
 	var (
-		IDColumn           = postgres.IntegerColumn("id")
-		TokenColumn        = postgres.StringColumn("token")
-		UserIDColumn       = postgres.IntegerColumn("user_id")
-		ExpiresColumn      = postgres.TimestampzColumn("expires")
-		LoginIPColumn      = postgres.StringColumn("login_ip")
-		LoginBrowserColumn = postgres.StringColumn("login_browser")
-		LoggedInColumn     = postgres.TimestampzColumn("logged_in")
-		LastSeenColumn     = postgres.TimestampzColumn("last_seen")
-		allColumns         = postgres.ColumnList{IDColumn, TokenColumn, UserIDColumn, ExpiresColumn, LoginIPColumn, LoginBrowserColumn, LoggedInColumn, LastSeenColumn}
-		mutableColumns     = postgres.ColumnList{TokenColumn, UserIDColumn, ExpiresColumn, LoginIPColumn, LoginBrowserColumn, LoggedInColumn, LastSeenColumn}
+		IDColumn             = postgres.IntegerColumn("id")
+		TokenColumn          = postgres.StringColumn("token")
+		UserIDColumn         = postgres.IntegerColumn("user_id")
+		ExpiresColumn        = postgres.TimestampzColumn("expires")
+		LoginIPColumn        = postgres.StringColumn("login_ip")
+		LoginBrowserColumn   = postgres.StringColumn("login_browser")
+		LoggedInColumn       = postgres.TimestampzColumn("logged_in")
+		LastSeenColumn       = postgres.TimestampzColumn("last_seen")
+		FamilyIDColumn       = postgres.IntegerColumn("family_id")
+		ImpersonatorIDColumn = postgres.IntegerColumn("impersonator_id")
+		NewDeviceColumn      = postgres.BoolColumn("new_device")
+		allColumns           = postgres.ColumnList{IDColumn, TokenColumn, UserIDColumn, ExpiresColumn, LoginIPColumn, LoginBrowserColumn, LoggedInColumn, LastSeenColumn, FamilyIDColumn, ImpersonatorIDColumn, NewDeviceColumn}
+		mutableColumns       = postgres.ColumnList{TokenColumn, UserIDColumn, ExpiresColumn, LoginIPColumn, LoginBrowserColumn, LoggedInColumn, LastSeenColumn, FamilyIDColumn, ImpersonatorIDColumn, NewDeviceColumn}
 	)
 
 	return sessionsTable{
 		Table: postgres.NewTable(schemaName, tableName, alias, allColumns...),
 
 		//Columns
-		ID:           IDColumn,
-		Token:        TokenColumn,
-		UserID:       UserIDColumn,
-		Expires:      ExpiresColumn,
-		LoginIP:      LoginIPColumn,
-		LoginBrowser: LoginBrowserColumn,
-		LoggedIn:     LoggedInColumn,
-		LastSeen:     LastSeenColumn,
+		ID:             IDColumn,
+		Token:          TokenColumn,
+		UserID:         UserIDColumn,
+		Expires:        ExpiresColumn,
+		LoginIP:        LoginIPColumn,
+		LoginBrowser:   LoginBrowserColumn,
+		LoggedIn:       LoggedInColumn,
+		LastSeen:       LastSeenColumn,
+		FamilyID:       FamilyIDColumn,
+		ImpersonatorID: ImpersonatorIDColumn,
+		NewDevice:      NewDeviceColumn,
 
 		AllColumns:     allColumns,
 		MutableColumns: mutableColumns,
@@ -17,20 +17,25 @@ type usersTable struct {
 	postgres.Table
 
 	//Columns
-	ID          postgres.ColumnInteger
-	Name        postgres.ColumnString
-	Email       postgres.ColumnString
-	PhoneNumber postgres.ColumnString
-	IDCode      postgres.ColumnInteger
-	BirthDate   postgres.ColumnDate
-	Password    postgres.ColumnString
-	Role        postgres.ColumnString
-	ClassID     postgres.ColumnInteger
-	CreatedAt   postgres.ColumnTimestampz
-	Active      postgres.ColumnBool
-	Archived    postgres.ColumnBool
-	TotpEnabled postgres.ColumnBool
-	TotpSecret  postgres.ColumnString
+	ID             postgres.ColumnInteger
+	Name           postgres.ColumnString
+	Email          postgres.ColumnString
+	PhoneNumber    postgres.ColumnString
+	IDCode         postgres.ColumnInteger
+	BirthDate      postgres.ColumnDate
+	Password       postgres.ColumnString
+	Role           postgres.ColumnString
+	ClassID        postgres.ColumnInteger
+	CreatedAt      postgres.ColumnTimestampz
+	Active         postgres.ColumnBool
+	Archived       postgres.ColumnBool
+	TotpEnabled    postgres.ColumnBool
+	TotpSecret     postgres.ColumnString
+	TotpLastStep   postgres.ColumnInteger
+	ExternalAuth   postgres.ColumnBool
+	ServiceAccount postgres.ColumnBool
+	ArchivedAt     postgres.ColumnTimestampz
+	Roles          postgres.ColumnString
 
 	AllColumns     postgres.ColumnList
 	MutableColumns postgres.ColumnList
@@ -71,42 +76,52 @@ func newUsersTable(schemaName, tableName, alias string) *UsersTable {
 
 func newUsersTableImpl(schemaName, tableName, alias string) usersTable {
 	var (
-		IDColumn          = postgres.IntegerColumn("id")
-		NameColumn        = postgres.StringColumn("name")
-		EmailColumn       = postgres.StringColumn("email")
-		PhoneNumberColumn = postgres.StringColumn("phone_number")
-		IDCodeColumn      = postgres.IntegerColumn("id_code")
-		BirthDateColumn   = postgres.DateColumn("birth_date")
-		PasswordColumn    = postgres.StringColumn("password")
-		RoleColumn        = postgres.StringColumn("role")
-		ClassIDColumn     = postgres.IntegerColumn("class_id")
-		CreatedAtColumn   = postgres.TimestampzColumn("created_at")
-		ActiveColumn      = postgres.BoolColumn("active")
-		ArchivedColumn    = postgres.BoolColumn("archived")
-		TotpEnabledColumn = postgres.BoolColumn("totp_enabled")
-		TotpSecretColumn  = postgres.StringColumn("totp_secret")
-		allColumns        = postgres.ColumnList{IDColumn, NameColumn, EmailColumn, PhoneNumberColumn, IDCodeColumn, BirthDateColumn, PasswordColumn, RoleColumn, ClassIDColumn, CreatedAtColumn, ActiveColumn, ArchivedColumn, TotpEnabledColumn, TotpSecretColumn}
-		mutableColumns    = postgres.ColumnList{NameColumn, EmailColumn, PhoneNumberColumn, IDCodeColumn, BirthDateColumn, PasswordColumn, RoleColumn, ClassIDColumn, CreatedAtColumn, ActiveColumn, ArchivedColumn, TotpEnabledColumn, TotpSecretColumn}
+		IDColumn             = postgres.IntegerColumn("id")
+		NameColumn           = postgres.StringColumn("name")
+		EmailColumn          = postgres.StringColumn("email")
+		PhoneNumberColumn    = postgres.StringColumn("phone_number")
+		IDCodeColumn         = postgres.IntegerColumn("id_code")
+		BirthDateColumn      = postgres.DateColumn("birth_date")
+		PasswordColumn       = postgres.StringColumn("password")
+		RoleColumn           = postgres.StringColumn("role")
+		ClassIDColumn        = postgres.IntegerColumn("class_id")
+		CreatedAtColumn      = postgres.TimestampzColumn("created_at")
+		ActiveColumn         = postgres.BoolColumn("active")
+		ArchivedColumn       = postgres.BoolColumn("archived")
+		TotpEnabledColumn    = postgres.BoolColumn("totp_enabled")
+		TotpSecretColumn     = postgres.StringColumn("totp_secret")
+		TotpLastStepColumn   = postgres.IntegerColumn("totp_last_step")
+		ExternalAuthColumn   = postgres.BoolColumn("external_auth")
+		ServiceAccountColumn = postgres.BoolColumn("service_account")
+		ArchivedAtColumn     = postgres.TimestampzColumn("archived_at")
+		RolesColumn          = postgres.StringColumn("roles")
+		allColumns           = postgres.ColumnList{IDColumn, NameColumn, EmailColumn, PhoneNumberColumn, IDCodeColumn, BirthDateColumn, PasswordColumn, RoleColumn, ClassIDColumn, CreatedAtColumn, ActiveColumn, ArchivedColumn, TotpEnabledColumn, TotpSecretColumn, TotpLastStepColumn, ExternalAuthColumn, ServiceAccountColumn, ArchivedAtColumn, RolesColumn}
+		mutableColumns       = postgres.ColumnList{NameColumn, EmailColumn, PhoneNumberColumn, IDCodeColumn, BirthDateColumn, PasswordColumn, RoleColumn, ClassIDColumn, CreatedAtColumn, ActiveColumn, ArchivedColumn, TotpEnabledColumn, TotpSecretColumn, TotpLastStepColumn, ExternalAuthColumn, ServiceAccountColumn, ArchivedAtColumn, RolesColumn}
 	)
 
 	return usersTable{
 		Table: postgres.NewTable(schemaName, tableName, alias, allColumns...),
 
 		//Columns
-		ID:          IDColumn,
-		Name:        NameColumn,
-		Email:       EmailColumn,
-		PhoneNumber: PhoneNumberColumn,
-		IDCode:      IDCodeColumn,
-		BirthDate:   BirthDateColumn,
-		Password:    PasswordColumn,
-		Role:        RoleColumn,
-		ClassID:     ClassIDColumn,
-		CreatedAt:   CreatedAtColumn,
-		Active:      ActiveColumn,
-		Archived:    ArchivedColumn,
-		TotpEnabled: TotpEnabledColumn,
-		TotpSecret:  TotpSecretColumn,
+		ID:             IDColumn,
+		Name:           NameColumn,
+		Email:          EmailColumn,
+		PhoneNumber:    PhoneNumberColumn,
+		IDCode:         IDCodeColumn,
+		BirthDate:      BirthDateColumn,
+		Password:       PasswordColumn,
+		Role:           RoleColumn,
+		ClassID:        ClassIDColumn,
+		CreatedAt:      CreatedAtColumn,
+		Active:         ActiveColumn,
+		Archived:       ArchivedColumn,
+		TotpEnabled:    TotpEnabledColumn,
+		TotpSecret:     TotpSecretColumn,
+		TotpLastStep:   TotpLastStepColumn,
+		ExternalAuth:   ExternalAuthColumn,
+		ServiceAccount: ServiceAccountColumn,
+		ArchivedAt:     ArchivedAtColumn,
+		Roles:          RolesColumn,
 
 		AllColumns:     allColumns,
 		MutableColumns: mutableColumns,
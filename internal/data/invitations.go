@@ -0,0 +1,212 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/annusingmar/lavurso-backend/internal/data/gen/lavurso/public/model"
+	"github.com/annusingmar/lavurso-backend/internal/data/gen/lavurso/public/table"
+	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/go-jet/jet/v2/postgres"
+	"github.com/go-jet/jet/v2/qrm"
+)
+
+var (
+	ErrNoSuchInvitation     = errors.New("no such invitation")
+	ErrInvalidInvitation    = errors.New("invalid or expired invitation")
+	ErrInvitationNotPending = errors.New("invitation has already been accepted or revoked")
+)
+
+type Invitation = model.Invitations
+
+type InvitationExt struct {
+	Invitation
+	User *User `json:"user"`
+}
+
+type InvitationModel struct {
+	DB *sql.DB
+}
+
+// pending invitations haven't been accepted or revoked, but may have expired
+func invitationPending() postgres.BoolExpression {
+	return table.Invitations.AcceptedAt.IS_NULL().AND(table.Invitations.RevokedAt.IS_NULL())
+}
+
+// insertInvitation is used to insert the invitation together with its user
+func insertInvitation(ctx context.Context, db qrm.Queryable, i *Invitation) error {
+	stmt := table.Invitations.INSERT(table.Invitations.UserID, table.Invitations.Token, table.Invitations.CreatedBy, table.Invitations.Expires).
+		MODEL(i).
+		RETURNING(table.Invitations.ID)
+
+	var id []int
+
+	err := stmt.QueryContext(ctx, db, &id)
+	if err != nil {
+		return err
+	}
+
+	i.ID = id[0]
+
+	return nil
+}
+
+func (m InvitationModel) GetPendingInvitations() ([]*InvitationExt, error) {
+	query := postgres.SELECT(table.Invitations.AllColumns.Except(table.Invitations.Token), table.Users.ID, table.Users.Name, table.Users.Email, table.Users.Role).
+		FROM(table.Invitations.
+			INNER_JOIN(table.Users, table.Users.ID.EQ(table.Invitations.UserID))).
+		WHERE(invitationPending()).
+		ORDER_BY(table.Invitations.CreatedAt.DESC())
+
+	var invitations []*InvitationExt
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &invitations)
+	if err != nil {
+		return nil, err
+	}
+
+	return invitations, nil
+}
+
+func (m InvitationModel) GetInvitationByID(invitationID int) (*Invitation, error) {
+	query := postgres.SELECT(table.Invitations.AllColumns.Except(table.Invitations.Token)).
+		FROM(table.Invitations).
+		WHERE(table.Invitations.ID.EQ(helpers.PostgresInt(invitationID)))
+
+	var invitation Invitation
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &invitation)
+	if err != nil {
+		switch {
+		case errors.Is(err, qrm.ErrNoRows):
+			return nil, ErrNoSuchInvitation
+		default:
+			return nil, err
+		}
+	}
+
+	return &invitation, nil
+}
+
+// GetInvitationByToken returns a pending and unexpired invitation
+func (m InvitationModel) GetInvitationByToken(plaintextToken string) (*Invitation, error) {
+	hash := sha256.Sum256([]byte(plaintextToken))
+
+	query := postgres.SELECT(table.Invitations.AllColumns.Except(table.Invitations.Token)).
+		FROM(table.Invitations).
+		WHERE(postgres.AND(
+			table.Invitations.Token.EQ(postgres.Bytea(hash[:])),
+			invitationPending(),
+			table.Invitations.Expires.GT(postgres.TimestampzT(time.Now().UTC())),
+		))
+
+	var invitation Invitation
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &invitation)
+	if err != nil {
+		switch {
+		case errors.Is(err, qrm.ErrNoRows):
+			return nil, ErrInvalidInvitation
+		default:
+			return nil, err
+		}
+	}
+
+	return &invitation, nil
+}
+
+// AcceptInvitation marks a pending and unexpired invitation as accepted,
+// so that the same invitation can't be accepted twice
+func (m InvitationModel) AcceptInvitation(invitationID int) error {
+	stmt := table.Invitations.UPDATE(table.Invitations.AcceptedAt).
+		SET(time.Now().UTC()).
+		WHERE(postgres.AND(
+			table.Invitations.ID.EQ(helpers.PostgresInt(invitationID)),
+			invitationPending(),
+			table.Invitations.Expires.GT(postgres.TimestampzT(time.Now().UTC())),
+		))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := stmt.ExecContext(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrInvalidInvitation
+	}
+
+	return nil
+}
+
+// RenewInvitation gives a pending invitation a new token and expiry
+func (m InvitationModel) RenewInvitation(i *Invitation) error {
+	stmt := table.Invitations.UPDATE(table.Invitations.Token, table.Invitations.Expires).
+		MODEL(i).
+		WHERE(table.Invitations.ID.EQ(helpers.PostgresInt(i.ID)).
+			AND(invitationPending()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := stmt.ExecContext(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrInvitationNotPending
+	}
+
+	return nil
+}
+
+func (m InvitationModel) RevokeInvitation(invitationID int) error {
+	stmt := table.Invitations.UPDATE(table.Invitations.RevokedAt).
+		SET(time.Now().UTC()).
+		WHERE(table.Invitations.ID.EQ(helpers.PostgresInt(invitationID)).
+			AND(invitationPending()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := stmt.ExecContext(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrInvitationNotPending
+	}
+
+	return nil
+}
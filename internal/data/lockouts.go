@@ -0,0 +1,231 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/annusingmar/lavurso-backend/internal/data/gen/lavurso/public/model"
+	"github.com/annusingmar/lavurso-backend/internal/data/gen/lavurso/public/table"
+	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/go-jet/jet/v2/postgres"
+	"github.com/go-jet/jet/v2/qrm"
+)
+
+var (
+	ErrNoSuchLockout     = errors.New("no such lockout")
+	ErrTooManyAttempts   = errors.New("too many failed login attempts, try again later")
+	ErrTemporarilyLocked = errors.New("login temporarily locked due to too many failed attempts")
+)
+
+type FailedLogin = model.FailedLogins
+
+type FailedLoginStats struct {
+	Count int
+	Last  *time.Time
+}
+
+type Lockout = model.Lockouts
+
+type LockoutExt struct {
+	Lockout
+	User *User `json:"user,omitempty"`
+}
+
+type LockoutModel struct {
+	DB *sql.DB
+}
+
+func (m LockoutModel) InsertFailedLogin(fl *FailedLogin) error {
+	stmt := table.FailedLogins.INSERT(table.FailedLogins.UserID, table.FailedLogins.IP).
+		MODEL(fl)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := stmt.ExecContext(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m LockoutModel) getFailedLoginStats(where postgres.BoolExpression) (*FailedLoginStats, error) {
+	query := postgres.SELECT(
+		postgres.COUNT(table.FailedLogins.ID).AS("failedloginstats.count"),
+		postgres.MAX(table.FailedLogins.At).AS("failedloginstats.last"),
+	).
+		FROM(table.FailedLogins).
+		WHERE(where)
+
+	var stats FailedLoginStats
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &stats)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+func (m LockoutModel) GetFailedLoginStatsForUser(userID int, since time.Time) (*FailedLoginStats, error) {
+	return m.getFailedLoginStats(table.FailedLogins.UserID.EQ(helpers.PostgresInt(userID)).
+		AND(table.FailedLogins.At.GT(postgres.TimestampzT(since))))
+}
+
+func (m LockoutModel) GetFailedLoginStatsForIP(ip string, since time.Time) (*FailedLoginStats, error) {
+	return m.getFailedLoginStats(table.FailedLogins.IP.EQ(postgres.String(ip)).
+		AND(table.FailedLogins.At.GT(postgres.TimestampzT(since))))
+}
+
+func (m LockoutModel) DeleteFailedLoginsForUser(userID int) error {
+	stmt := table.FailedLogins.DELETE().
+		WHERE(table.FailedLogins.UserID.EQ(helpers.PostgresInt(userID)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := stmt.ExecContext(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m LockoutModel) DeleteFailedLoginsForIP(ip string) error {
+	stmt := table.FailedLogins.DELETE().
+		WHERE(table.FailedLogins.IP.EQ(postgres.String(ip)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := stmt.ExecContext(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m LockoutModel) InsertLockout(l *Lockout) error {
+	stmt := table.Lockouts.INSERT(table.Lockouts.UserID, table.Lockouts.IP, table.Lockouts.LockedUntil).
+		MODEL(l).
+		RETURNING(table.Lockouts.ID)
+
+	var id []int
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := stmt.QueryContext(ctx, m.DB, &id)
+	if err != nil {
+		return err
+	}
+
+	l.ID = id[0]
+
+	return nil
+}
+
+func activeLockout() postgres.BoolExpression {
+	return table.Lockouts.LockedUntil.GT(postgres.TimestampzT(time.Now().UTC())).
+		AND(table.Lockouts.ClearedAt.IS_NULL())
+}
+
+// GetActiveLockout returns the longest running active lockout
+// for either the user or the IP address, or nil if there is none
+func (m LockoutModel) GetActiveLockout(userID *int, ip string) (*Lockout, error) {
+	target := table.Lockouts.IP.EQ(postgres.String(ip))
+	if userID != nil {
+		target = target.OR(table.Lockouts.UserID.EQ(helpers.PostgresInt(*userID)))
+	}
+
+	query := postgres.SELECT(table.Lockouts.AllColumns).
+		FROM(table.Lockouts).
+		WHERE(activeLockout().AND(target)).
+		ORDER_BY(table.Lockouts.LockedUntil.DESC()).
+		LIMIT(1)
+
+	var lockout Lockout
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &lockout)
+	if err != nil {
+		switch {
+		case errors.Is(err, qrm.ErrNoRows):
+			return nil, nil
+		default:
+			return nil, err
+		}
+	}
+
+	return &lockout, nil
+}
+
+func (m LockoutModel) AllActiveLockouts() ([]*LockoutExt, error) {
+	query := postgres.SELECT(table.Lockouts.AllColumns, table.Users.ID, table.Users.Name, table.Users.Email).
+		FROM(table.Lockouts.
+			LEFT_JOIN(table.Users, table.Users.ID.EQ(table.Lockouts.UserID))).
+		WHERE(activeLockout()).
+		ORDER_BY(table.Lockouts.CreatedAt.DESC())
+
+	var lockouts []*LockoutExt
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &lockouts)
+	if err != nil {
+		return nil, err
+	}
+
+	return lockouts, nil
+}
+
+func (m LockoutModel) GetActiveLockoutByID(lockoutID int) (*Lockout, error) {
+	query := postgres.SELECT(table.Lockouts.AllColumns).
+		FROM(table.Lockouts).
+		WHERE(table.Lockouts.ID.EQ(helpers.PostgresInt(lockoutID)).
+			AND(activeLockout()))
+
+	var lockout Lockout
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &lockout)
+	if err != nil {
+		switch {
+		case errors.Is(err, qrm.ErrNoRows):
+			return nil, ErrNoSuchLockout
+		default:
+			return nil, err
+		}
+	}
+
+	return &lockout, nil
+}
+
+func (m LockoutModel) ClearLockout(lockoutID, clearedBy int) error {
+	stmt := table.Lockouts.UPDATE(table.Lockouts.ClearedBy, table.Lockouts.ClearedAt).
+		SET(clearedBy, time.Now().UTC()).
+		WHERE(table.Lockouts.ID.EQ(helpers.PostgresInt(lockoutID)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := stmt.ExecContext(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
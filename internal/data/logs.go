@@ -10,6 +10,14 @@ import (
 	"github.com/go-jet/jet/v2/postgres"
 )
 
+const (
+	LogEventFailedLogin = "failed_login"
+	LogEventLockout     = "lockout"
+	LogEventImpersonate = "impersonate"
+	LogEventExport      = "export"
+	LogEventAnonymize   = "anonymize"
+)
+
 type Log = model.Logs
 
 type LogExt struct {
@@ -3,37 +3,61 @@ package data
 import "database/sql"
 
 type Models struct {
-	Users       UserModel
-	Classes     ClassModel
-	Subjects    SubjectModel
-	Journals    JournalModel
-	Lessons     LessonModel
-	Assignments AssignmentModel
-	Grades      GradeModel
-	Marks       MarkModel
-	Absences    AbsenceModel
-	Groups      GroupModel
-	Messaging   MessagingModel
-	Sessions    SessionModel
-	Years       YearModel
-	Logs        LogModel
+	Users              UserModel
+	Classes            ClassModel
+	Subjects           SubjectModel
+	Journals           JournalModel
+	Lessons            LessonModel
+	Assignments        AssignmentModel
+	Grades             GradeModel
+	Marks              MarkModel
+	Absences           AbsenceModel
+	Groups             GroupModel
+	Messaging          MessagingModel
+	Sessions           SessionModel
+	Years              YearModel
+	Logs               LogModel
+	PasswordResets     PasswordResetModel
+	Lockouts           LockoutModel
+	RecoveryCodes      RecoveryCodeModel
+	Passkeys           PasskeyModel
+	ExternalIdentities ExternalIdentityModel
+	APITokens          APITokenModel
+	PasswordHistory    PasswordHistoryModel
+	Invitations        InvitationModel
+	Export             ExportModel
+	Anonymizations     AnonymizationModel
+	ClassMemberships   ClassMembershipModel
+	Rollovers          RolloverModel
 }
 
 func NewModel(db *sql.DB) Models {
 	return Models{
-		Users:       UserModel{DB: db},
-		Classes:     ClassModel{DB: db},
-		Subjects:    SubjectModel{DB: db},
-		Journals:    JournalModel{DB: db},
-		Lessons:     LessonModel{DB: db},
-		Assignments: AssignmentModel{DB: db},
-		Grades:      GradeModel{DB: db},
-		Marks:       MarkModel{DB: db},
-		Absences:    AbsenceModel{DB: db},
-		Groups:      GroupModel{DB: db},
-		Messaging:   MessagingModel{DB: db},
-		Sessions:    SessionModel{DB: db},
-		Years:       YearModel{DB: db},
-		Logs:        LogModel{DB: db},
+		Users:              UserModel{DB: db},
+		Classes:            ClassModel{DB: db},
+		Subjects:           SubjectModel{DB: db},
+		Journals:           JournalModel{DB: db},
+		Lessons:            LessonModel{DB: db},
+		Assignments:        AssignmentModel{DB: db},
+		Grades:             GradeModel{DB: db},
+		Marks:              MarkModel{DB: db},
+		Absences:           AbsenceModel{DB: db},
+		Groups:             GroupModel{DB: db},
+		Messaging:          MessagingModel{DB: db},
+		Sessions:           SessionModel{DB: db},
+		Years:              YearModel{DB: db},
+		Logs:               LogModel{DB: db},
+		PasswordResets:     PasswordResetModel{DB: db},
+		Lockouts:           LockoutModel{DB: db},
+		RecoveryCodes:      RecoveryCodeModel{DB: db},
+		Passkeys:           PasskeyModel{DB: db},
+		ExternalIdentities: ExternalIdentityModel{DB: db},
+		APITokens:          APITokenModel{DB: db},
+		PasswordHistory:    PasswordHistoryModel{DB: db},
+		Invitations:        InvitationModel{DB: db},
+		Export:             ExportModel{DB: db},
+		Anonymizations:     AnonymizationModel{DB: db},
+		ClassMemberships:   ClassMembershipModel{DB: db},
+		Rollovers:          RolloverModel{DB: db},
 	}
 }
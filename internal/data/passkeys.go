@@ -0,0 +1,266 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/annusingmar/lavurso-backend/internal/data/gen/lavurso/public/model"
+	"github.com/annusingmar/lavurso-backend/internal/data/gen/lavurso/public/table"
+	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/go-jet/jet/v2/postgres"
+	"github.com/go-jet/jet/v2/qrm"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+var (
+	ErrNoSuchPasskey  = errors.New("no such passkey")
+	ErrInvalidPasskey = errors.New("invalid passkey")
+	ErrPasskeyExists  = errors.New("passkey already registered")
+)
+
+type Passkey = model.Passkeys
+
+type PasskeyChallenge = model.PasskeyChallenges
+
+// WebAuthnUser implements webauthn.User
+type WebAuthnUser struct {
+	ID          int
+	Name        string
+	DisplayName string
+	Credentials []webauthn.Credential
+}
+
+func (u *WebAuthnUser) WebAuthnID() []byte {
+	return []byte(strconv.Itoa(u.ID))
+}
+
+func (u *WebAuthnUser) WebAuthnName() string {
+	return u.Name
+}
+
+func (u *WebAuthnUser) WebAuthnDisplayName() string {
+	return u.DisplayName
+}
+
+func (u *WebAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.Credentials
+}
+
+func (u *WebAuthnUser) WebAuthnIcon() string {
+	return ""
+}
+
+// ParseWebAuthnUserHandle returns the user ID from a user handle
+// created by WebAuthnUser.WebAuthnID
+func ParseWebAuthnUserHandle(userHandle []byte) (int, error) {
+	return strconv.Atoi(string(userHandle))
+}
+
+type PasskeyModel struct {
+	DB *sql.DB
+}
+
+func (m PasskeyModel) InsertPasskey(userID int, name string, credential *webauthn.Credential) error {
+	credentialJSON, err := json.Marshal(credential)
+	if err != nil {
+		return err
+	}
+
+	stmt := table.Passkeys.INSERT(table.Passkeys.UserID, table.Passkeys.Name, table.Passkeys.CredentialID, table.Passkeys.Credential).
+		VALUES(userID, name, postgres.Bytea(credential.ID), string(credentialJSON))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err = stmt.ExecContext(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m PasskeyModel) GetPasskeysForUser(userID int) ([]*Passkey, error) {
+	query := postgres.SELECT(table.Passkeys.ID, table.Passkeys.Name, table.Passkeys.CreatedAt, table.Passkeys.LastUsedAt).
+		FROM(table.Passkeys).
+		WHERE(table.Passkeys.UserID.EQ(helpers.PostgresInt(userID))).
+		ORDER_BY(table.Passkeys.CreatedAt.ASC())
+
+	var passkeys []*Passkey
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &passkeys)
+	if err != nil {
+		return nil, err
+	}
+
+	return passkeys, nil
+}
+
+func (m PasskeyModel) GetPasskeyByID(passkeyID int) (*Passkey, error) {
+	query := postgres.SELECT(table.Passkeys.ID, table.Passkeys.UserID, table.Passkeys.Name, table.Passkeys.CreatedAt, table.Passkeys.LastUsedAt).
+		FROM(table.Passkeys).
+		WHERE(table.Passkeys.ID.EQ(helpers.PostgresInt(passkeyID)))
+
+	var passkey Passkey
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &passkey)
+	if err != nil {
+		switch {
+		case errors.Is(err, qrm.ErrNoRows):
+			return nil, ErrNoSuchPasskey
+		default:
+			return nil, err
+		}
+	}
+
+	return &passkey, nil
+}
+
+func (m PasskeyModel) GetCredentialsForUser(userID int) ([]webauthn.Credential, error) {
+	query := postgres.SELECT(table.Passkeys.Credential).
+		FROM(table.Passkeys).
+		WHERE(table.Passkeys.UserID.EQ(helpers.PostgresInt(userID)))
+
+	var credentialJSON []string
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &credentialJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := make([]webauthn.Credential, len(credentialJSON))
+	for i, c := range credentialJSON {
+		err = json.Unmarshal([]byte(c), &credentials[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return credentials, nil
+}
+
+// UpdatePasskeyCredential stores the credential's new sign count and flags after a login
+func (m PasskeyModel) UpdatePasskeyCredential(credential *webauthn.Credential) error {
+	credentialJSON, err := json.Marshal(credential)
+	if err != nil {
+		return err
+	}
+
+	stmt := table.Passkeys.UPDATE(table.Passkeys.Credential, table.Passkeys.LastUsedAt).
+		SET(string(credentialJSON), time.Now().UTC()).
+		WHERE(table.Passkeys.CredentialID.EQ(postgres.Bytea(credential.ID)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err = stmt.ExecContext(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m PasskeyModel) DeletePasskey(passkeyID int) error {
+	stmt := table.Passkeys.DELETE().
+		WHERE(table.Passkeys.ID.EQ(helpers.PostgresInt(passkeyID)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := stmt.ExecContext(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// InsertPasskeyChallenge stores the ceremony's session data until it is finished.
+// userID is nil for login ceremonies, where the user isn't known beforehand.
+func (m PasskeyModel) InsertPasskeyChallenge(session *webauthn.SessionData, userID *int) error {
+	sessionJSON, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	deleteStmt := table.PasskeyChallenges.DELETE().
+		WHERE(table.PasskeyChallenges.Expires.LT(postgres.TimestampzT(time.Now().UTC())))
+
+	insertStmt := table.PasskeyChallenges.INSERT(table.PasskeyChallenges.MutableColumns).
+		MODEL(PasskeyChallenge{
+			Challenge:   &session.Challenge,
+			UserID:      userID,
+			SessionData: helpers.ToPtr(string(sessionJSON)),
+			Expires:     &session.Expires,
+		})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err = deleteStmt.ExecContext(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+
+	_, err = insertStmt.ExecContext(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UsePasskeyChallenge deletes the unexpired challenge and returns its session data,
+// so every challenge can only be answered once
+func (m PasskeyModel) UsePasskeyChallenge(challenge string, userID *int) (*webauthn.SessionData, error) {
+	owner := table.PasskeyChallenges.UserID.IS_NULL()
+	if userID != nil {
+		owner = table.PasskeyChallenges.UserID.EQ(helpers.PostgresInt(*userID))
+	}
+
+	stmt := table.PasskeyChallenges.DELETE().
+		WHERE(postgres.AND(
+			table.PasskeyChallenges.Challenge.EQ(postgres.String(challenge)),
+			table.PasskeyChallenges.Expires.GT(postgres.TimestampzT(time.Now().UTC())),
+			owner,
+		)).
+		RETURNING(table.PasskeyChallenges.SessionData)
+
+	var pc PasskeyChallenge
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := stmt.QueryContext(ctx, m.DB, &pc)
+	if err != nil {
+		switch {
+		case errors.Is(err, qrm.ErrNoRows):
+			return nil, ErrInvalidPasskey
+		default:
+			return nil, err
+		}
+	}
+
+	var session webauthn.SessionData
+
+	err = json.Unmarshal([]byte(*pc.SessionData), &session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
@@ -0,0 +1,73 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/annusingmar/lavurso-backend/internal/data/gen/lavurso/public/model"
+	"github.com/annusingmar/lavurso-backend/internal/data/gen/lavurso/public/table"
+	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/annusingmar/lavurso-backend/internal/types"
+	"github.com/go-jet/jet/v2/postgres"
+)
+
+type PasswordHistory = model.PasswordHistory
+
+type PasswordHistoryModel struct {
+	DB *sql.DB
+}
+
+// InsertPasswordHistory saves a user's previous password hash
+// and deletes all but the keep most recent ones
+func (m PasswordHistoryModel) InsertPasswordHistory(userID int, password *types.Password, keep int) error {
+	stmt := table.PasswordHistory.INSERT(table.PasswordHistory.UserID, table.PasswordHistory.Password).
+		MODEL(&PasswordHistory{UserID: &userID, Password: password})
+
+	deleteStmt := table.PasswordHistory.DELETE().
+		WHERE(postgres.AND(
+			table.PasswordHistory.UserID.EQ(helpers.PostgresInt(userID)),
+			table.PasswordHistory.ID.NOT_IN(
+				postgres.SELECT(table.PasswordHistory.ID).
+					FROM(table.PasswordHistory).
+					WHERE(table.PasswordHistory.UserID.EQ(helpers.PostgresInt(userID))).
+					ORDER_BY(table.PasswordHistory.CreatedAt.DESC(), table.PasswordHistory.ID.DESC()).
+					LIMIT(int64(keep)),
+			),
+		))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := stmt.ExecContext(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+
+	_, err = deleteStmt.ExecContext(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m PasswordHistoryModel) GetPasswordHistory(userID, limit int) ([]*PasswordHistory, error) {
+	query := postgres.SELECT(table.PasswordHistory.AllColumns).
+		FROM(table.PasswordHistory).
+		WHERE(table.PasswordHistory.UserID.EQ(helpers.PostgresInt(userID))).
+		ORDER_BY(table.PasswordHistory.CreatedAt.DESC(), table.PasswordHistory.ID.DESC()).
+		LIMIT(int64(limit))
+
+	var history []*PasswordHistory
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &history)
+	if err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
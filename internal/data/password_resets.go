@@ -0,0 +1,127 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/annusingmar/lavurso-backend/internal/data/gen/lavurso/public/model"
+	"github.com/annusingmar/lavurso-backend/internal/data/gen/lavurso/public/table"
+	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/go-jet/jet/v2/postgres"
+	"github.com/go-jet/jet/v2/qrm"
+)
+
+var (
+	ErrInvalidResetToken = errors.New("invalid or expired reset token")
+)
+
+type PasswordReset = model.PasswordResets
+
+type PasswordResetModel struct {
+	DB *sql.DB
+}
+
+func (m PasswordResetModel) InsertPasswordReset(pr *PasswordReset) error {
+	stmt := table.PasswordResets.INSERT(table.PasswordResets.UserID, table.PasswordResets.Token, table.PasswordResets.Expires).
+		MODEL(pr).
+		RETURNING(table.PasswordResets.ID)
+
+	var id []int
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := stmt.QueryContext(ctx, m.DB, &id)
+	if err != nil {
+		return err
+	}
+
+	pr.ID = id[0]
+
+	return nil
+}
+
+// GetUserIDByResetToken returns the ID of the user an unused and unexpired reset token was issued for
+func (m PasswordResetModel) GetUserIDByResetToken(plaintextToken string) (int, error) {
+	hash := sha256.Sum256([]byte(plaintextToken))
+
+	query := postgres.SELECT(table.PasswordResets.UserID).
+		FROM(table.PasswordResets).
+		WHERE(postgres.AND(
+			table.PasswordResets.Token.EQ(postgres.Bytea(hash[:])),
+			table.PasswordResets.UsedAt.IS_NULL(),
+			table.PasswordResets.Expires.GT(postgres.TimestampzT(time.Now().UTC())),
+		))
+
+	var pr PasswordReset
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &pr)
+	if err != nil {
+		switch {
+		case errors.Is(err, qrm.ErrNoRows):
+			return 0, ErrInvalidResetToken
+		default:
+			return 0, err
+		}
+	}
+
+	return *pr.UserID, nil
+}
+
+// UsePasswordReset marks an unused and unexpired reset token as used
+// and returns the ID of the user it was issued for
+func (m PasswordResetModel) UsePasswordReset(plaintextToken string) (int, error) {
+	hash := sha256.Sum256([]byte(plaintextToken))
+
+	stmt := table.PasswordResets.UPDATE(table.PasswordResets.UsedAt).
+		SET(time.Now().UTC()).
+		WHERE(postgres.AND(
+			table.PasswordResets.Token.EQ(postgres.Bytea(hash[:])),
+			table.PasswordResets.UsedAt.IS_NULL(),
+			table.PasswordResets.Expires.GT(postgres.TimestampzT(time.Now().UTC())),
+		)).
+		RETURNING(table.PasswordResets.UserID)
+
+	var pr PasswordReset
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := stmt.QueryContext(ctx, m.DB, &pr)
+	if err != nil {
+		switch {
+		case errors.Is(err, qrm.ErrNoRows):
+			return 0, ErrInvalidResetToken
+		default:
+			return 0, err
+		}
+	}
+
+	return *pr.UserID, nil
+}
+
+func (m PasswordResetModel) ExpireAllPasswordResetsByUserID(userID int) error {
+	stmt := table.PasswordResets.UPDATE(table.PasswordResets.Expires).
+		SET(time.Now().UTC()).
+		WHERE(postgres.AND(
+			table.PasswordResets.UserID.EQ(helpers.PostgresInt(userID)),
+			table.PasswordResets.UsedAt.IS_NULL(),
+			table.PasswordResets.Expires.GT(postgres.TimestampzT(time.Now().UTC())),
+		))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := stmt.ExecContext(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/annusingmar/lavurso-backend/internal/data/gen/lavurso/public/model"
+	"github.com/annusingmar/lavurso-backend/internal/data/gen/lavurso/public/table"
+	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/annusingmar/lavurso-backend/internal/types"
+	"github.com/go-jet/jet/v2/postgres"
+)
+
+const RecoveryCodeCount = 10
+
+var (
+	ErrInvalidRecoveryCode = errors.New("invalid recovery code")
+)
+
+type RecoveryCode = model.RecoveryCodes
+
+type RecoveryCodeModel struct {
+	DB *sql.DB
+}
+
+// NewRecoveryCodesForUser replaces all of the user's recovery codes
+// with new ones and returns them in plaintext
+func (m RecoveryCodeModel) NewRecoveryCodesForUser(userID int) ([]string, error) {
+	var plaintexts []string
+	var codes []RecoveryCode
+
+	for i := 0; i < RecoveryCodeCount; i++ {
+		code, err := types.GenerateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		plaintexts = append(plaintexts, code)
+		codes = append(codes, RecoveryCode{
+			UserID: &userID,
+			Code:   types.HashRecoveryCode(code),
+		})
+	}
+
+	deleteStmt := table.RecoveryCodes.DELETE().
+		WHERE(table.RecoveryCodes.UserID.EQ(helpers.PostgresInt(userID)))
+
+	insertStmt := table.RecoveryCodes.INSERT(table.RecoveryCodes.UserID, table.RecoveryCodes.Code).
+		MODELS(codes)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = deleteStmt.ExecContext(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = insertStmt.ExecContext(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return plaintexts, nil
+}
+
+// UseRecoveryCode marks the user's unused recovery code as used,
+// returning ErrInvalidRecoveryCode if there is no such code
+func (m RecoveryCodeModel) UseRecoveryCode(userID int, code string) error {
+	stmt := table.RecoveryCodes.UPDATE(table.RecoveryCodes.UsedAt).
+		SET(time.Now().UTC()).
+		WHERE(postgres.AND(
+			table.RecoveryCodes.UserID.EQ(helpers.PostgresInt(userID)),
+			table.RecoveryCodes.Code.EQ(postgres.Bytea(types.HashRecoveryCode(code))),
+			table.RecoveryCodes.UsedAt.IS_NULL(),
+		))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := stmt.ExecContext(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrInvalidRecoveryCode
+	}
+
+	return nil
+}
+
+func (m RecoveryCodeModel) CountRemainingRecoveryCodes(userID int) (int, error) {
+	query := postgres.SELECT(postgres.COUNT(table.RecoveryCodes.ID)).
+		FROM(table.RecoveryCodes).
+		WHERE(table.RecoveryCodes.UserID.EQ(helpers.PostgresInt(userID)).
+			AND(table.RecoveryCodes.UsedAt.IS_NULL()))
+
+	var count []int
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count[0], nil
+}
+
+func (m RecoveryCodeModel) DeleteRecoveryCodesForUser(userID int) error {
+	stmt := table.RecoveryCodes.DELETE().
+		WHERE(table.RecoveryCodes.UserID.EQ(helpers.PostgresInt(userID)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := stmt.ExecContext(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,400 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/annusingmar/lavurso-backend/internal/data/gen/lavurso/public/model"
+	"github.com/annusingmar/lavurso-backend/internal/data/gen/lavurso/public/table"
+	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/go-jet/jet/v2/postgres"
+	"github.com/go-jet/jet/v2/qrm"
+)
+
+var (
+	ErrNoRollover        = errors.New("no rollover to undo")
+	ErrUndoWindowPassed  = errors.New("rollover can no longer be undone")
+	ErrRolloverYearInUse = errors.New("rolled over year is already in use")
+)
+
+type Rollover = model.Rollovers
+
+// RolloverPlan describes a new school year. NewClasses are created for it,
+// TransferredClasses continue into it under new names and all other classes are archived.
+type RolloverPlan struct {
+	DisplayName        string
+	NewClasses         []*ClassExt
+	TransferredClasses []*ClassYear
+	RolledOverBy       *int
+}
+
+// RolloverReport describes what a rollover changed, or would change in a dry run
+type RolloverReport struct {
+	Year                *Year            `json:"year"`
+	Renamed             []*RolloverClass `json:"renamed"`
+	Created             []*RolloverClass `json:"created"`
+	Archived            []*RolloverClass `json:"archived"`
+	TransferredStudents int              `json:"transferred_students"`
+	ArchivedStudents    int              `json:"archived_students"`
+}
+
+type RolloverClass struct {
+	ID             int     `json:"id,omitempty" sql:"primary_key" alias:"classes.id"`
+	Name           *string `json:"name" alias:"classes.name"`
+	OldDisplayName *string `json:"old_display_name,omitempty" alias:"classes_years.display_name"`
+	DisplayName    *string `json:"display_name,omitempty" alias:"rolloverclass.display_name"`
+	Students       int     `json:"students" alias:"rolloverclass.students"`
+}
+
+type RolloverModel struct {
+	DB *sql.DB
+}
+
+// RollOver starts a new school year in one transaction: it inserts the year and the new classes,
+// names the classes in the year, makes it current, moves the students of transferred classes
+// into it and archives the users of all other classes. A dry run reports the changes
+// without keeping them.
+func (m RolloverModel) RollOver(plan *RolloverPlan, dryRun bool) (*RolloverReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var previous Year
+
+	err = postgres.SELECT(table.Years.AllColumns).
+		FROM(table.Years).
+		WHERE(table.Years.Current.IS_TRUE()).
+		FOR(postgres.UPDATE()).
+		QueryContext(ctx, tx, &previous)
+	if err != nil && !errors.Is(err, qrm.ErrNoRows) {
+		return nil, err
+	}
+
+	students := postgres.SELECT(postgres.COUNT(table.Users.ID)).
+		FROM(table.Users).
+		WHERE(table.Users.ClassID.EQ(table.Classes.ID).
+			AND(table.Users.Archived.IS_FALSE()))
+
+	var classes []*RolloverClass
+
+	err = postgres.SELECT(table.Classes.ID, table.Classes.Name, table.ClassesYears.DisplayName, students.AS("rolloverclass.students")).
+		FROM(table.Classes.
+			LEFT_JOIN(table.Years, table.Years.Current.IS_TRUE()).
+			LEFT_JOIN(table.ClassesYears, table.ClassesYears.ClassID.EQ(table.Classes.ID).
+				AND(table.ClassesYears.YearID.EQ(table.Years.ID)))).
+		ORDER_BY(table.Classes.Name.ASC()).
+		QueryContext(ctx, tx, &classes)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RolloverReport{
+		Renamed:  []*RolloverClass{},
+		Created:  []*RolloverClass{},
+		Archived: []*RolloverClass{},
+	}
+
+	newNames := make(map[int]*string)
+	for _, tc := range plan.TransferredClasses {
+		newNames[*tc.ClassID] = tc.DisplayName
+	}
+
+	var transferredIDs []postgres.Expression
+	var archivedIDs []postgres.Expression
+
+	for _, c := range classes {
+		if name, ok := newNames[c.ID]; ok {
+			transferredIDs = append(transferredIDs, helpers.PostgresInt(c.ID))
+			report.TransferredStudents += c.Students
+
+			if c.OldDisplayName == nil || *c.OldDisplayName != *name {
+				c.DisplayName = name
+				report.Renamed = append(report.Renamed, c)
+			}
+			continue
+		}
+
+		archivedIDs = append(archivedIDs, helpers.PostgresInt(c.ID))
+		report.ArchivedStudents += c.Students
+
+		if c.OldDisplayName != nil || c.Students > 0 {
+			report.Archived = append(report.Archived, c)
+		}
+	}
+
+	year := &Year{
+		DisplayName: &plan.DisplayName,
+		Current:     helpers.ToPtr(false),
+	}
+
+	err = table.Years.INSERT(table.Years.MutableColumns).
+		MODEL(year).
+		RETURNING(table.Years.ID, table.Years.DisplayName).
+		QueryContext(ctx, tx, year)
+	if err != nil {
+		return nil, err
+	}
+
+	report.Year = year
+
+	var classesYears []*ClassYear
+
+	for _, nc := range plan.NewClasses {
+		class := &Class{Name: nc.Name}
+
+		err = table.Classes.INSERT(table.Classes.Name).
+			MODEL(class).
+			RETURNING(table.Classes.ID).
+			QueryContext(ctx, tx, class)
+		if err != nil {
+			return nil, err
+		}
+
+		report.Created = append(report.Created, &RolloverClass{ID: class.ID, Name: nc.Name, DisplayName: nc.DisplayName})
+		classesYears = append(classesYears, &ClassYear{ClassID: &class.ID, YearID: &year.ID, DisplayName: nc.DisplayName})
+	}
+
+	for _, tc := range plan.TransferredClasses {
+		classesYears = append(classesYears, &ClassYear{ClassID: tc.ClassID, YearID: &year.ID, DisplayName: tc.DisplayName})
+	}
+
+	if len(classesYears) > 0 {
+		_, err = table.ClassesYears.INSERT(table.ClassesYears.AllColumns).
+			MODELS(classesYears).
+			ExecContext(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = table.Years.UPDATE(table.Years.Current).
+		SET(postgres.Bool(false)).
+		WHERE(table.Years.Current.IS_TRUE()).
+		ExecContext(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = table.Years.UPDATE(table.Years.Current).
+		SET(postgres.Bool(true)).
+		WHERE(table.Years.ID.EQ(helpers.PostgresInt(year.ID))).
+		ExecContext(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	year.Current = helpers.ToPtr(true)
+
+	rollover := &Rollover{
+		YearID:       &year.ID,
+		RolledOverBy: plan.RolledOverBy,
+	}
+	if previous.ID != 0 {
+		rollover.PreviousYearID = &previous.ID
+	}
+
+	err = table.Rollovers.INSERT(table.Rollovers.YearID, table.Rollovers.PreviousYearID, table.Rollovers.RolledOverBy).
+		MODEL(rollover).
+		RETURNING(table.Rollovers.ID).
+		QueryContext(ctx, tx, rollover)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(report.Created) > 0 {
+		var created []model.RolloversClasses
+		for _, c := range report.Created {
+			created = append(created, model.RolloversClasses{RolloverID: &rollover.ID, ClassID: &c.ID})
+		}
+
+		_, err = table.RolloversClasses.INSERT(table.RolloversClasses.AllColumns).
+			MODELS(created).
+			ExecContext(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if transferredIDs != nil {
+		_, err = table.ClassMemberships.INSERT(table.ClassMemberships.StudentID, table.ClassMemberships.ClassID, table.ClassMemberships.YearID).
+			QUERY(postgres.SELECT(table.Users.ID, table.Users.ClassID, helpers.PostgresInt(year.ID)).
+				FROM(table.Users).
+				WHERE(table.Users.Role.EQ(postgres.String(RoleStudent)).
+					AND(table.Users.Archived.IS_FALSE()).
+					AND(table.Users.ClassID.IN(transferredIDs...)))).
+			ExecContext(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if archivedIDs != nil {
+		// the archived users are remembered, so an undo only restores them
+		_, err = table.RolloversUsers.INSERT(table.RolloversUsers.RolloverID, table.RolloversUsers.UserID).
+			QUERY(postgres.SELECT(helpers.PostgresInt(rollover.ID), table.Users.ID).
+				FROM(table.Users).
+				WHERE(table.Users.ClassID.IN(archivedIDs...).
+					AND(table.Users.Archived.IS_FALSE()))).
+			ExecContext(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = table.Users.UPDATE(table.Users.Archived, table.Users.ArchivedAt).
+			SET(postgres.Bool(true), postgres.NOW()).
+			WHERE(table.Users.ClassID.IN(archivedIDs...).
+				AND(table.Users.Archived.IS_FALSE())).
+			ExecContext(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if dryRun {
+		// the IDs of rolled back rows would never exist
+		year.ID = 0
+		for _, c := range report.Created {
+			c.ID = 0
+		}
+		return report, nil
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func (m RolloverModel) GetLatestRollover() (*Rollover, error) {
+	query := postgres.SELECT(table.Rollovers.AllColumns).
+		FROM(table.Rollovers).
+		ORDER_BY(table.Rollovers.ID.DESC()).
+		LIMIT(1)
+
+	var rollover Rollover
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &rollover)
+	if err != nil {
+		switch {
+		case errors.Is(err, qrm.ErrNoRows):
+			return nil, ErrNoRollover
+		default:
+			return nil, err
+		}
+	}
+
+	return &rollover, nil
+}
+
+// UndoRollover reverts the latest rollover if it happened within window and nothing
+// has been done in its year yet: the year and the classes created for it are deleted,
+// the users it archived are restored and the previous year is made current again
+func (m RolloverModel) UndoRollover(window time.Duration) (*Rollover, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var rollover Rollover
+
+	err = postgres.SELECT(table.Rollovers.AllColumns).
+		FROM(table.Rollovers.
+			INNER_JOIN(table.Years, table.Years.ID.EQ(table.Rollovers.YearID))).
+		WHERE(table.Years.Current.IS_TRUE()).
+		ORDER_BY(table.Rollovers.ID.DESC()).
+		LIMIT(1).
+		FOR(postgres.UPDATE()).
+		QueryContext(ctx, tx, &rollover)
+	if err != nil {
+		switch {
+		case errors.Is(err, qrm.ErrNoRows):
+			return nil, ErrNoRollover
+		default:
+			return nil, err
+		}
+	}
+
+	if time.Since(*rollover.At) > window {
+		return nil, ErrUndoWindowPassed
+	}
+
+	yid := helpers.PostgresInt(*rollover.YearID)
+	rid := helpers.PostgresInt(rollover.ID)
+
+	createdClasses := postgres.SELECT(table.RolloversClasses.ClassID).
+		FROM(table.RolloversClasses).
+		WHERE(table.RolloversClasses.RolloverID.EQ(rid))
+
+	// journals, transfers and new students in the year would be lost with it
+	var inUse []bool
+
+	err = postgres.SELECT(postgres.EXISTS(postgres.SELECT(table.Journals.ID).FROM(table.Journals).WHERE(table.Journals.YearID.EQ(yid))).
+		OR(postgres.EXISTS(postgres.SELECT(table.ClassMemberships.ID).FROM(table.ClassMemberships).
+			WHERE(table.ClassMemberships.YearID.EQ(yid).AND(table.ClassMemberships.StartDate.IS_NOT_NULL())))).
+		OR(postgres.EXISTS(postgres.SELECT(table.Users.ID).FROM(table.Users).WHERE(table.Users.ClassID.IN(createdClasses))))).
+		QueryContext(ctx, tx, &inUse)
+	if err != nil {
+		return nil, err
+	}
+
+	if inUse[0] {
+		return nil, ErrRolloverYearInUse
+	}
+
+	_, err = table.Users.UPDATE(table.Users.Archived, table.Users.ArchivedAt).
+		SET(postgres.Bool(false), postgres.NULL).
+		WHERE(table.Users.ID.IN(postgres.SELECT(table.RolloversUsers.UserID).
+			FROM(table.RolloversUsers).
+			WHERE(table.RolloversUsers.RolloverID.EQ(rid)))).
+		ExecContext(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = table.Classes.DELETE().
+		WHERE(table.Classes.ID.IN(createdClasses)).
+		ExecContext(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the year's class names, memberships and the rollover itself are deleted with it
+	_, err = table.Years.DELETE().
+		WHERE(table.Years.ID.EQ(yid)).
+		ExecContext(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if rollover.PreviousYearID != nil {
+		_, err = table.Years.UPDATE(table.Years.Current).
+			SET(postgres.Bool(true)).
+			WHERE(table.Years.ID.EQ(helpers.PostgresInt(*rollover.PreviousYearID))).
+			ExecContext(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return &rollover, nil
+}
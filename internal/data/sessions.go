@@ -2,6 +2,7 @@ package data
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
 	"errors"
 	"time"
@@ -9,17 +10,35 @@ import (
 	"github.com/annusingmar/lavurso-backend/internal/data/gen/lavurso/public/model"
 	"github.com/annusingmar/lavurso-backend/internal/data/gen/lavurso/public/table"
 	"github.com/annusingmar/lavurso-backend/internal/helpers"
+	"github.com/annusingmar/lavurso-backend/internal/useragent"
 	"github.com/go-jet/jet/v2/postgres"
 	"github.com/go-jet/jet/v2/qrm"
 )
 
 var (
-	ErrInvalidToken  = errors.New("invalid token")
-	ErrNoSuchSession = errors.New("no such session")
+	ErrInvalidToken       = errors.New("invalid token")
+	ErrNoSuchSession      = errors.New("no such session")
+	ErrRefreshTokenReused = errors.New("refresh token already used, session revoked")
 )
 
 type Session = model.Sessions
 
+type SessionExt struct {
+	Session
+	Impersonator *User            `json:"impersonator,omitempty" alias:"impersonator"`
+	Current      *bool            `json:"current,omitempty"`
+	Agent        *useragent.Agent `json:"agent,omitempty"`
+}
+
+type SessionFamily = model.SessionFamilies
+
+type RefreshToken = model.RefreshTokens
+
+type RefreshTokenExt struct {
+	RefreshToken
+	Family SessionFamily `json:"-"`
+}
+
 type SessionModel struct {
 	DB *sql.DB
 }
@@ -44,11 +63,18 @@ func (m SessionModel) InsertSession(s *Session) error {
 	return nil
 }
 
-func (m SessionModel) ExtendSession(sessionID int) error {
+// ExtendSession moves the session's expiry idleTimeout forward from now,
+// but never past absoluteLifetime from when the session was created
+func (m SessionModel) ExtendSession(sessionID int, idleTimeout, absoluteLifetime time.Duration) error {
 	current := time.Now().UTC()
 
+	var expires postgres.Expression = postgres.TimestampzT(current.Add(idleTimeout))
+	if absoluteLifetime > 0 {
+		expires = postgres.LEAST(expires, table.Sessions.LoggedIn.ADD(postgres.INTERVALd(absoluteLifetime)))
+	}
+
 	stmt := table.Sessions.UPDATE(table.Sessions.LastSeen, table.Sessions.Expires).
-		SET(current, current.Add(3*time.Minute)).
+		SET(current, expires).
 		WHERE(table.Sessions.ID.EQ(helpers.PostgresInt(sessionID)))
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -67,6 +93,14 @@ func (m SessionModel) ExpireSessionByID(sessionID int) error {
 		SET(time.Now().UTC()).
 		WHERE(table.Sessions.ID.EQ(helpers.PostgresInt(sessionID)))
 
+	familyStmt := table.SessionFamilies.UPDATE(table.SessionFamilies.RevokedAt).
+		SET(time.Now().UTC()).
+		WHERE(table.SessionFamilies.ID.IN(
+			postgres.SELECT(table.Sessions.FamilyID).
+				FROM(table.Sessions).
+				WHERE(table.Sessions.ID.EQ(helpers.PostgresInt(sessionID))),
+		).AND(table.SessionFamilies.RevokedAt.IS_NULL()))
+
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
@@ -75,6 +109,11 @@ func (m SessionModel) ExpireSessionByID(sessionID int) error {
 		return err
 	}
 
+	_, err = familyStmt.ExecContext(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -84,6 +123,11 @@ func (m SessionModel) ExpireAllSessionsByUserID(userID int) error {
 		WHERE(table.Sessions.UserID.EQ(helpers.PostgresInt(userID)).
 			AND(table.Sessions.Expires.GT(postgres.TimestampzT(time.Now().UTC()))))
 
+	familyStmt := table.SessionFamilies.UPDATE(table.SessionFamilies.RevokedAt).
+		SET(time.Now().UTC()).
+		WHERE(table.SessionFamilies.UserID.EQ(helpers.PostgresInt(userID)).
+			AND(table.SessionFamilies.RevokedAt.IS_NULL()))
+
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
@@ -92,6 +136,11 @@ func (m SessionModel) ExpireAllSessionsByUserID(userID int) error {
 		return err
 	}
 
+	_, err = familyStmt.ExecContext(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -104,6 +153,19 @@ func (m SessionModel) ExpireAllSessionsByUserIDExceptOne(userID, sessionID int)
 			table.Sessions.Expires.GT(postgres.TimestampzT(time.Now().UTC())),
 		))
 
+	familyStmt := table.SessionFamilies.UPDATE(table.SessionFamilies.RevokedAt).
+		SET(time.Now().UTC()).
+		WHERE(postgres.AND(
+			table.SessionFamilies.UserID.EQ(helpers.PostgresInt(userID)),
+			table.SessionFamilies.RevokedAt.IS_NULL(),
+			table.SessionFamilies.ID.NOT_IN(
+				postgres.SELECT(table.Sessions.FamilyID).
+					FROM(table.Sessions).
+					WHERE(table.Sessions.ID.EQ(helpers.PostgresInt(sessionID)).
+						AND(table.Sessions.FamilyID.IS_NOT_NULL())),
+			),
+		))
+
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
@@ -112,6 +174,11 @@ func (m SessionModel) ExpireAllSessionsByUserIDExceptOne(userID, sessionID int)
 		return err
 	}
 
+	_, err = familyStmt.ExecContext(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -134,6 +201,80 @@ func (m SessionModel) GetSessionsByUserID(userID int) ([]*Session, error) {
 	return sessions, nil
 }
 
+// GetActiveSessionsByUserID returns the user's unexpired sessions, most recently used first
+func (m SessionModel) GetActiveSessionsByUserID(userID int) ([]*SessionExt, error) {
+	impersonator := table.Users.AS("impersonator")
+
+	query := postgres.SELECT(table.Sessions.AllColumns.Except(table.Sessions.Token), impersonator.ID, impersonator.Name, impersonator.Role).
+		FROM(table.Sessions.
+			LEFT_JOIN(impersonator, impersonator.ID.EQ(table.Sessions.ImpersonatorID))).
+		WHERE(table.Sessions.UserID.EQ(helpers.PostgresInt(userID)).
+			AND(table.Sessions.Expires.GT(postgres.TimestampzT(time.Now().UTC())))).
+		ORDER_BY(table.Sessions.LastSeen.DESC())
+
+	var sessions []*SessionExt
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &sessions)
+	if err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// GetLoginBrowsersByUserID returns the distinct User-Agents the user has logged in with
+func (m SessionModel) GetLoginBrowsersByUserID(userID int) ([]string, error) {
+	query := postgres.SELECT(table.Sessions.LoginBrowser).DISTINCT().
+		FROM(table.Sessions).
+		WHERE(table.Sessions.UserID.EQ(helpers.PostgresInt(userID)).
+			AND(table.Sessions.ImpersonatorID.IS_NULL()))
+
+	var sessions []*Session
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &sessions)
+	if err != nil {
+		return nil, err
+	}
+
+	var browsers []string
+	for _, s := range sessions {
+		if s.LoginBrowser != nil {
+			browsers = append(browsers, *s.LoginBrowser)
+		}
+	}
+
+	return browsers, nil
+}
+
+// GetImpersonationsByUserID returns the sessions admins have started as the user
+func (m SessionModel) GetImpersonationsByUserID(userID int) ([]*SessionExt, error) {
+	impersonator := table.Users.AS("impersonator")
+
+	query := postgres.SELECT(table.Sessions.AllColumns.Except(table.Sessions.Token), impersonator.ID, impersonator.Name, impersonator.Role).
+		FROM(table.Sessions.
+			INNER_JOIN(impersonator, impersonator.ID.EQ(table.Sessions.ImpersonatorID))).
+		WHERE(table.Sessions.UserID.EQ(helpers.PostgresInt(userID))).
+		ORDER_BY(table.Sessions.LoggedIn.DESC())
+
+	var sessions []*SessionExt
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &sessions)
+	if err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
 func (m SessionModel) GetSessionByID(sessionID int) (*Session, error) {
 	query := postgres.SELECT(table.Sessions.AllColumns).
 		FROM(table.Sessions).
@@ -157,3 +298,171 @@ func (m SessionModel) GetSessionByID(sessionID int) (*Session, error) {
 
 	return &session, nil
 }
+
+func (m SessionModel) InsertSessionFamily(f *SessionFamily) error {
+	stmt := table.SessionFamilies.INSERT(table.SessionFamilies.UserID).
+		MODEL(f).
+		RETURNING(table.SessionFamilies.ID)
+
+	var id []int
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := stmt.QueryContext(ctx, m.DB, &id)
+	if err != nil {
+		return err
+	}
+
+	f.ID = id[0]
+
+	return nil
+}
+
+func (m SessionModel) InsertRefreshToken(rt *RefreshToken) error {
+	stmt := table.RefreshTokens.INSERT(table.RefreshTokens.Token, table.RefreshTokens.FamilyID, table.RefreshTokens.SessionID, table.RefreshTokens.Expires).
+		MODEL(rt).
+		RETURNING(table.RefreshTokens.ID)
+
+	var id []int
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := stmt.QueryContext(ctx, m.DB, &id)
+	if err != nil {
+		return err
+	}
+
+	rt.ID = id[0]
+
+	return nil
+}
+
+// GetRefreshToken returns the refresh token along with its family,
+// including tokens that were already used, so reuse can be detected
+func (m SessionModel) GetRefreshToken(plaintextToken string) (*RefreshTokenExt, error) {
+	hash := sha256.Sum256([]byte(plaintextToken))
+
+	query := postgres.SELECT(table.RefreshTokens.AllColumns, table.SessionFamilies.AllColumns).
+		FROM(table.RefreshTokens.
+			INNER_JOIN(table.SessionFamilies, table.SessionFamilies.ID.EQ(table.RefreshTokens.FamilyID))).
+		WHERE(table.RefreshTokens.Token.EQ(postgres.Bytea(hash[:])))
+
+	var rt RefreshTokenExt
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &rt)
+	if err != nil {
+		switch {
+		case errors.Is(err, qrm.ErrNoRows):
+			return nil, ErrInvalidToken
+		default:
+			return nil, err
+		}
+	}
+
+	return &rt, nil
+}
+
+// RevokeSessionFamily expires all sessions and refresh tokens
+// that were issued from the same login
+func (m SessionModel) RevokeSessionFamily(familyID int) error {
+	familyStmt := table.SessionFamilies.UPDATE(table.SessionFamilies.RevokedAt).
+		SET(time.Now().UTC()).
+		WHERE(table.SessionFamilies.ID.EQ(helpers.PostgresInt(familyID)).
+			AND(table.SessionFamilies.RevokedAt.IS_NULL()))
+
+	sessionStmt := table.Sessions.UPDATE(table.Sessions.Expires).
+		SET(time.Now().UTC()).
+		WHERE(table.Sessions.FamilyID.EQ(helpers.PostgresInt(familyID)).
+			AND(table.Sessions.Expires.GT(postgres.TimestampzT(time.Now().UTC()))))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := familyStmt.ExecContext(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+
+	_, err = sessionStmt.ExecContext(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RotateRefreshToken marks the old refresh token as used, expires its session
+// and inserts the new session and refresh token in the same family
+func (m SessionModel) RotateRefreshToken(old *RefreshTokenExt, s *Session, rt *RefreshToken) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	useStmt := table.RefreshTokens.UPDATE(table.RefreshTokens.UsedAt).
+		SET(time.Now().UTC()).
+		WHERE(table.RefreshTokens.ID.EQ(helpers.PostgresInt(old.ID)).
+			AND(table.RefreshTokens.UsedAt.IS_NULL()))
+
+	res, err := useStmt.ExecContext(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrRefreshTokenReused
+	}
+
+	expireStmt := table.Sessions.UPDATE(table.Sessions.Expires).
+		SET(time.Now().UTC()).
+		WHERE(table.Sessions.ID.EQ(helpers.PostgresInt(*old.SessionID)).
+			AND(table.Sessions.Expires.GT(postgres.TimestampzT(time.Now().UTC()))))
+
+	_, err = expireStmt.ExecContext(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	sessionStmt := table.Sessions.INSERT(table.Sessions.MutableColumns).
+		MODEL(s).
+		RETURNING(table.Sessions.ID)
+
+	var sessionID []int
+
+	err = sessionStmt.QueryContext(ctx, tx, &sessionID)
+	if err != nil {
+		return err
+	}
+
+	s.ID = sessionID[0]
+	rt.SessionID = &s.ID
+
+	tokenStmt := table.RefreshTokens.INSERT(table.RefreshTokens.Token, table.RefreshTokens.FamilyID, table.RefreshTokens.SessionID, table.RefreshTokens.Expires).
+		MODEL(rt).
+		RETURNING(table.RefreshTokens.ID)
+
+	var tokenID []int
+
+	err = tokenStmt.QueryContext(ctx, tx, &tokenID)
+	if err != nil {
+		return err
+	}
+
+	rt.ID = tokenID[0]
+
+	return tx.Commit()
+}
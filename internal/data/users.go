@@ -5,7 +5,9 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"errors"
+	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -36,6 +38,7 @@ var (
 	ErrNotAStudent         = errors.New("not a student")
 	ErrNoSuchParentForUser = errors.New("no such parent set for child")
 	ErrNotAParent          = errors.New("not a parent")
+	ErrNotAServiceAccount  = errors.New("not a service account")
 	ErrMissingOTP          = errors.New("missing OTP")
 	ErrInvalidOTP          = errors.New("invalid OTP")
 	Err2FAAlreadyEnabled   = errors.New("2fa already enabled")
@@ -54,9 +57,19 @@ type Student struct {
 
 type UserExt struct {
 	User
-	Student       *Student `json:"student,omitempty"`
-	HasTOTPSecret *bool    `json:"has_totp_secret,omitempty"`
-	SessionID     *int     `json:"-" alias:"sessions.id"`
+	Student        *Student  `json:"student,omitempty"`
+	HasTOTPSecret  *bool     `json:"has_totp_secret,omitempty"`
+	SessionID      *int      `json:"-" alias:"sessions.id"`
+	ImpersonatorID *int      `json:"-" alias:"sessions.impersonator_id"`
+	APIToken       *APIToken `json:"-"`
+	Total          *int      `json:"-"`
+}
+
+// ParentLink links a parent to their child in ImportUsers,
+// either can be a user that is inserted in the same import
+type ParentLink struct {
+	Parent *User
+	Child  *User
 }
 
 type Role struct {
@@ -68,22 +81,99 @@ type UserModel struct {
 	DB *sql.DB
 }
 
+// userHasRole matches users who have the role, also if it isn't their primary role
+func userHasRole(role string) postgres.BoolExpression {
+	return postgres.CAST(postgres.Raw("#role = ANY(string_to_array(users.roles, ' '))", postgres.RawArgs{"#role": role})).AS_BOOL()
+}
+
 // DATABASE
 
-func (m UserModel) AllUsers(archived bool) ([]*UserExt, error) {
-	query := postgres.SELECT(table.Users.AllColumns.Except(table.Users.Password), table.Classes.Name, table.ClassesYears.DisplayName).
+// UserFilter narrows down AllUsers, nil fields aren't filtered by
+type UserFilter struct {
+	Role          *string
+	ClassID       *int
+	Active        *bool
+	Archived      *bool
+	TotpEnabled   *bool
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+}
+
+// UserSorts are the columns users can be sorted by
+var UserSorts = map[string]postgres.Expression{
+	"id":         table.Users.ID,
+	"name":       table.Users.Name,
+	"email":      table.Users.Email,
+	"role":       table.Users.Role,
+	"class":      table.Classes.Name,
+	"created_at": table.Users.CreatedAt,
+}
+
+type UsersWithTotal struct {
+	Users []*UserExt `json:"users,omitempty"`
+	Total int        `json:"total"`
+}
+
+// AllUsers returns a page of users matching the filter, sorted by a key of UserSorts.
+// If limit is 0, all users are returned.
+func (m UserModel) AllUsers(filter *UserFilter, sort string, descending bool, page, limit int) (*UsersWithTotal, error) {
+	query := postgres.SELECT(postgres.COUNT(postgres.STAR).OVER().AS("userext.total"),
+		table.Users.AllColumns.Except(table.Users.Password), table.Classes.Name, table.ClassesYears.DisplayName).
 		FROM(table.Users.
 			LEFT_JOIN(table.Years, table.Years.Current.IS_TRUE()).
 			LEFT_JOIN(table.Classes, table.Classes.ID.EQ(table.Users.ClassID)).
 			LEFT_JOIN(table.ClassesYears,
 				table.ClassesYears.ClassID.EQ(table.Classes.ID).
-					AND(table.ClassesYears.YearID.EQ(table.Years.ID)))).
-		WHERE(table.Users.Archived.EQ(postgres.Bool(archived))).
-		ORDER_BY(table.Users.ID.ASC())
+					AND(table.ClassesYears.YearID.EQ(table.Years.ID))))
+
+	var conditions []postgres.BoolExpression
+
+	if filter.Role != nil {
+		conditions = append(conditions, userHasRole(*filter.Role))
+	}
+	if filter.ClassID != nil {
+		conditions = append(conditions, table.Users.ClassID.EQ(helpers.PostgresInt(*filter.ClassID)))
+	}
+	if filter.Active != nil {
+		conditions = append(conditions, table.Users.Active.EQ(postgres.Bool(*filter.Active)))
+	}
+	if filter.Archived != nil {
+		conditions = append(conditions, table.Users.Archived.EQ(postgres.Bool(*filter.Archived)))
+	}
+	if filter.TotpEnabled != nil {
+		conditions = append(conditions, table.Users.TotpEnabled.EQ(postgres.Bool(*filter.TotpEnabled)))
+	}
+	if filter.CreatedAfter != nil {
+		conditions = append(conditions, table.Users.CreatedAt.GT_EQ(postgres.TimestampzT(*filter.CreatedAfter)))
+	}
+	if filter.CreatedBefore != nil {
+		conditions = append(conditions, table.Users.CreatedAt.LT(postgres.TimestampzT(*filter.CreatedBefore)))
+	}
+
+	if len(conditions) > 0 {
+		query = query.WHERE(postgres.AND(conditions...))
+	}
+
+	column, ok := UserSorts[sort]
+	if !ok {
+		column = table.Users.ID
+	}
+
+	// users are sorted by id last, so pages don't overlap
+	if descending {
+		query = query.ORDER_BY(column.DESC(), table.Users.ID.DESC())
+	} else {
+		query = query.ORDER_BY(column.ASC(), table.Users.ID.ASC())
+	}
+
+	if limit != 0 {
+		offset := (page - 1) * limit
+		query = query.OFFSET(int64(offset)).LIMIT(int64(limit))
+	}
 
 	var users []*UserExt
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	err := query.QueryContext(ctx, m.DB, &users)
@@ -91,34 +181,175 @@ func (m UserModel) AllUsers(archived bool) ([]*UserExt, error) {
 		return nil, err
 	}
 
-	return users, nil
+	u := &UsersWithTotal{
+		Users: users,
+	}
+
+	if len(u.Users) > 0 {
+		u.Total = *u.Users[0].Total
+	} else {
+		u.Total = 0
+	}
+
+	return u, nil
 }
 
-func (m UserModel) SearchUser(name string) ([]*UserExt, error) {
-	// todo: LIKE + LOWER -> ILIKE
+// UserSearch is a fuzzy search for users by name, and by email and ID code if Private is set
+type UserSearch struct {
+	Query   string
+	Role    *string
+	ClassID *int
+	Private bool
+	// Threshold is the minimum word similarity of a match, between 0 and 1
+	Threshold float64
+	Limit     int
+}
 
-	query := postgres.SELECT(table.Users.ID, table.Users.Name, table.Users.Role, table.Users.ClassID, table.Classes.Name, table.ClassesYears.DisplayName).
+type UserSearchResult struct {
+	UserExt
+	Score   *float64 `json:"score" alias:"usersearchresult.score"`
+	Matches []string `json:"matches"`
+
+	NameMatch   *bool `json:"-" alias:"usersearchresult.name_match"`
+	EmailMatch  *bool `json:"-" alias:"usersearchresult.email_match"`
+	IDCodeMatch *bool `json:"-" alias:"usersearchresult.id_code_match"`
+}
+
+var digitsRegex = regexp.MustCompile(`^\d+$`)
+
+// SearchUser ranks users by how similar their name or email is to the query,
+// ignoring case and Estonian diacritics. ID codes match by prefix.
+func (m UserModel) SearchUser(search *UserSearch) ([]*UserSearchResult, error) {
+	// jet requires every argument to appear in the raw expression
+	args := func(expr string) postgres.RawArgs {
+		a := postgres.RawArgs{}
+		if strings.Contains(expr, "#query") {
+			a["#query"] = search.Query
+		}
+		if strings.Contains(expr, "#prefix") {
+			a["#prefix"] = search.Query + "%"
+		}
+		return a
+	}
+
+	// search_normalize is created in migration 044, the trigram indexes are on its results
+	nameMatch := "search_normalize(#query) <% search_normalize(users.name)"
+	nameScore := "word_similarity(search_normalize(#query), search_normalize(users.name))"
+	emailMatch, emailScore := "FALSE", "0"
+	idCodeMatch := "FALSE"
+
+	if search.Private {
+		emailMatch = "search_normalize(#query) <% search_normalize(users.email::text)"
+		emailScore = "word_similarity(search_normalize(#query), search_normalize(users.email::text))"
+		if digitsRegex.MatchString(search.Query) {
+			idCodeMatch = "users.id_code::text LIKE #prefix"
+		}
+	}
+
+	conditions := []postgres.BoolExpression{
+		postgres.CAST(postgres.Raw(fmt.Sprintf("(%s) OR (%s) OR (%s)", nameMatch, emailMatch, idCodeMatch), args(nameMatch+emailMatch+idCodeMatch))).AS_BOOL(),
+		table.Users.Archived.IS_FALSE(),
+	}
+
+	if search.Role != nil {
+		conditions = append(conditions, userHasRole(*search.Role))
+	}
+	if search.ClassID != nil {
+		conditions = append(conditions, table.Users.ClassID.EQ(helpers.PostgresInt(*search.ClassID)))
+	}
+
+	scoreExpr := fmt.Sprintf("GREATEST(%s, CASE WHEN %s THEN %s ELSE 0 END, CASE WHEN %s THEN 1 ELSE 0 END)",
+		nameScore, emailMatch, emailScore, idCodeMatch)
+
+	query := postgres.SELECT(table.Users.ID, table.Users.Name, table.Users.Role, table.Users.ClassID, table.Classes.Name, table.ClassesYears.DisplayName,
+		postgres.RawFloat(scoreExpr, args(scoreExpr)).AS("usersearchresult.score"),
+		postgres.CAST(postgres.Raw(nameMatch, args(nameMatch))).AS_BOOL().AS("usersearchresult.name_match"),
+		postgres.CAST(postgres.Raw(emailMatch, args(emailMatch))).AS_BOOL().AS("usersearchresult.email_match"),
+		postgres.CAST(postgres.Raw(idCodeMatch, args(idCodeMatch))).AS_BOOL().AS("usersearchresult.id_code_match"),
+	).
 		FROM(table.Users.
 			LEFT_JOIN(table.Years, table.Years.Current.IS_TRUE()).
 			LEFT_JOIN(table.Classes, table.Classes.ID.EQ(table.Users.ClassID)).
 			LEFT_JOIN(table.ClassesYears, table.ClassesYears.ClassID.EQ(table.Classes.ID).AND(table.ClassesYears.YearID.EQ(table.Years.ID)))).
-		WHERE(postgres.LOWER(table.Users.Name).LIKE(postgres.LOWER(postgres.String("%" + name + "%"))).
-			AND(table.Users.Archived.IS_FALSE())).
-		ORDER_BY(table.Users.Name.ASC())
+		WHERE(postgres.AND(conditions...)).
+		ORDER_BY(postgres.FloatColumn("usersearchresult.score").DESC(), table.Users.Name.ASC()).
+		LIMIT(int64(search.Limit))
 
-	var users []*UserExt
+	var users []*UserSearchResult
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := query.QueryContext(ctx, m.DB, &users)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// the <% operator matches when the word similarity is at least this
+	_, err = postgres.SELECT(postgres.Func("set_config",
+		postgres.String("pg_trgm.word_similarity_threshold"),
+		postgres.String(strconv.FormatFloat(search.Threshold, 'f', -1, 64)),
+		postgres.Bool(true),
+	)).ExecContext(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = query.QueryContext(ctx, tx, &users)
 	if err != nil {
 		return nil, err
 	}
 
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, u := range users {
+		u.Matches = []string{}
+		if *u.NameMatch {
+			u.Matches = append(u.Matches, "name")
+		}
+		if *u.EmailMatch {
+			u.Matches = append(u.Matches, "email")
+		}
+		if *u.IDCodeMatch {
+			u.Matches = append(u.Matches, "id_code")
+		}
+	}
+
 	return users, nil
 }
 
+// GetUserByIDCode finds the user with the ID code, including archived users
+func (m UserModel) GetUserByIDCode(idCode int64) (*UserExt, error) {
+	query := postgres.SELECT(table.Users.ID, table.Users.Name, table.Users.Email, table.Users.PhoneNumber, table.Users.IDCode, table.Users.BirthDate, table.Users.Role, table.Users.ClassID, table.Users.Active, table.Users.Archived,
+		table.Classes.ID, table.Classes.Name, table.ClassesYears.DisplayName).
+		FROM(table.Users.
+			LEFT_JOIN(table.Years, table.Years.Current.IS_TRUE()).
+			LEFT_JOIN(table.Classes, table.Classes.ID.EQ(table.Users.ClassID)).
+			LEFT_JOIN(table.ClassesYears, table.ClassesYears.ClassID.EQ(table.Classes.ID).AND(table.ClassesYears.YearID.EQ(table.Years.ID)))).
+		WHERE(table.Users.IDCode.EQ(postgres.Int64(idCode)))
+
+	var user UserExt
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &user)
+	if err != nil {
+		switch {
+		case errors.Is(err, qrm.ErrNoRows):
+			return nil, ErrNoSuchUser
+		default:
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
+
 func (m UserModel) GetUserByID(userID int) (*UserExt, error) {
 	parent := table.Users.AS("parents")
 
@@ -155,9 +386,9 @@ func (m UserModel) GetUserByID(userID int) (*UserExt, error) {
 }
 
 func (m UserModel) GetUsersByRole(role string) ([]*UserExt, error) {
-	query := postgres.SELECT(table.Users.ID, table.Users.Name, table.Users.Role).
+	query := postgres.SELECT(table.Users.ID, table.Users.Name, table.Users.Role, table.Users.Roles).
 		FROM(table.Users).
-		WHERE(table.Users.Role.EQ(postgres.String(role))).
+		WHERE(userHasRole(role)).
 		ORDER_BY(table.Users.ID.ASC())
 
 	var users []*UserExt
@@ -173,7 +404,8 @@ func (m UserModel) GetUsersByRole(role string) ([]*UserExt, error) {
 	return users, nil
 }
 
-func (m UserModel) InsertUser(u *User) error {
+// InsertUser inserts the user, a student's membership in their current class and the invitation for them if it's given
+func (m UserModel) InsertUser(u *User, invitation *Invitation) error {
 	stmt := table.Users.INSERT(table.Users.MutableColumns.
 		Except(table.Users.CreatedAt, table.Users.Active, table.Users.Archived)).
 		MODEL(u).
@@ -182,7 +414,13 @@ func (m UserModel) InsertUser(u *User) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := stmt.QueryContext(ctx, m.DB, u)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = stmt.QueryContext(ctx, tx, u)
 
 	if err != nil {
 		var pgErr *pgconn.PgError
@@ -198,11 +436,32 @@ func (m UserModel) InsertUser(u *User) error {
 		}
 	}
 
+	if *u.Role == RoleStudent && u.ClassID != nil {
+		err = insertCurrentMembership(ctx, tx, u.ID, *u.ClassID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if invitation != nil {
+		invitation.UserID = &u.ID
+
+		err = insertInvitation(ctx, tx, invitation)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
 func (m UserModel) UpdateUser(u *UserExt) error {
-	stmt := table.Users.UPDATE(table.Users.MutableColumns).
+	stmt := table.Users.UPDATE(table.Users.MutableColumns.Except(table.Users.TotpLastStep)).
 		MODEL(u).
 		WHERE(table.Users.ID.EQ(helpers.PostgresInt(u.ID)))
 
@@ -229,6 +488,48 @@ func (m UserModel) UpdateUser(u *UserExt) error {
 
 }
 
+// UpdateUserWithTransfer updates the student and moves them to their new class in the year from date,
+// both or neither are saved
+func (m UserModel) UpdateUserWithTransfer(u *UserExt, year *Year, date *types.Date) error {
+	stmt := table.Users.UPDATE(table.Users.MutableColumns.Except(table.Users.TotpLastStep)).
+		MODEL(u).
+		WHERE(table.Users.ID.EQ(helpers.PostgresInt(u.ID)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = stmt.ExecContext(ctx, tx)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			if strings.Contains(pgErr.Message, "email") {
+				return ErrEmailAlreadyExists
+			} else if strings.Contains(pgErr.Message, "id_code") {
+				return ErrIDCodeAlreadyExists
+			}
+		}
+		return err
+	}
+
+	err = transferStudent(ctx, tx, u.ID, *u.ClassID, year, date)
+	if err != nil && !errors.Is(err, ErrAlreadyInClass) {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (m UserModel) GetAllUserIDs() ([]int, error) {
 	query := postgres.SELECT(table.Users.ID).
 		FROM(table.Users).
@@ -268,7 +569,7 @@ func (m UserModel) GetAllStudentIDs() ([]int, error) {
 func (m UserModel) GetUserBySessionToken(plaintextToken string) (*UserExt, error) {
 	hash := sha256.Sum256([]byte(plaintextToken))
 
-	query := postgres.SELECT(table.Users.AllColumns, table.Classes.Name, table.Sessions.ID).
+	query := postgres.SELECT(table.Users.AllColumns, table.Classes.Name, table.Sessions.ID, table.Sessions.ImpersonatorID).
 		FROM(table.Users.
 			LEFT_JOIN(table.Classes, table.Classes.ID.EQ(table.Users.ClassID)).
 			INNER_JOIN(table.Sessions, table.Sessions.UserID.EQ(table.Users.ID))).
@@ -297,6 +598,36 @@ func (m UserModel) GetUserBySessionToken(plaintextToken string) (*UserExt, error
 	return &user, nil
 }
 
+// GetUserByAPIToken returns the token's user along with the token's ID and scopes
+func (m UserModel) GetUserByAPIToken(plaintextToken string) (*UserExt, error) {
+	query := postgres.SELECT(table.Users.AllColumns, table.Classes.Name, table.APITokens.ID, table.APITokens.Scopes).
+		FROM(table.Users.
+			LEFT_JOIN(table.Classes, table.Classes.ID.EQ(table.Users.ClassID)).
+			INNER_JOIN(table.APITokens, table.APITokens.UserID.EQ(table.Users.ID))).
+		WHERE(postgres.AND(
+			table.Users.Archived.IS_FALSE(),
+			table.Users.Active.IS_TRUE(),
+			table.APITokens.Token.EQ(postgres.Bytea(HashAPIToken(plaintextToken))),
+			table.APITokens.Expires.GT(postgres.TimestampzT(time.Now().UTC()))))
+
+	var user UserExt
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &user)
+	if err != nil {
+		switch {
+		case errors.Is(err, qrm.ErrNoRows):
+			return nil, ErrInvalidToken
+		default:
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
+
 func (m UserModel) GetUserByEmail(email string) (*UserExt, error) {
 	query := postgres.SELECT(table.Users.AllColumns).
 		FROM(table.Users).
@@ -513,22 +844,6 @@ func (m UserModel) IsUserParentOfStudent(studentID, userID int) (bool, error) {
 	return result[0] > 0, nil
 }
 
-func (m UserModel) ArchiveUsersByClassID(classID int) error {
-	stmt := table.Users.UPDATE(table.Users.Archived).
-		SET(postgres.Bool(true)).
-		WHERE(table.Users.ClassID.EQ(helpers.PostgresInt(classID)))
-
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	_, err := stmt.ExecContext(ctx, m.DB)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
 func (m UserModel) AddTOTPTokenToUser(userID int) (types.TOTPSecret, error) {
 	token, err := types.GenerateSecret()
 	if err != nil {
@@ -550,6 +865,30 @@ func (m UserModel) AddTOTPTokenToUser(userID int) (types.TOTPSecret, error) {
 	return token, nil
 }
 
+// UseTOTPStep records step as the last accepted TOTP step for the user.
+// It returns false if the same or a later step was already used.
+func (m UserModel) UseTOTPStep(userID int, step int64) (bool, error) {
+	stmt := table.Users.UPDATE(table.Users.TotpLastStep).
+		SET(postgres.Int64(step)).
+		WHERE(table.Users.ID.EQ(helpers.PostgresInt(userID)).
+			AND(table.Users.TotpLastStep.IS_NULL().OR(table.Users.TotpLastStep.LT(postgres.Int64(step)))))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := stmt.ExecContext(ctx, m.DB)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows > 0, nil
+}
+
 func (m UserModel) Enable2FAForUser(userID int) error {
 	stmt := table.Users.UPDATE(table.Users.TotpEnabled).
 		SET(postgres.Bool(true)).
@@ -581,3 +920,145 @@ func (m UserModel) Disable2FAForUser(userID int) error {
 
 	return nil
 }
+
+func (m UserModel) UpdatePassword(userID int, password *types.Password) error {
+	stmt := table.Users.UPDATE(table.Users.Password).
+		SET(postgres.Bytea(password.Hashed)).
+		WHERE(table.Users.ID.EQ(helpers.PostgresInt(userID)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := stmt.ExecContext(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m UserModel) GetUsersByEmails(emails []string) ([]*User, error) {
+	var emailExpressions []postgres.Expression
+	for _, e := range emails {
+		emailExpressions = append(emailExpressions, postgres.String(e))
+	}
+
+	if len(emailExpressions) == 0 {
+		return nil, nil
+	}
+
+	query := postgres.SELECT(table.Users.AllColumns.Except(table.Users.Password)).
+		FROM(table.Users).
+		WHERE(table.Users.Email.IN(emailExpressions...))
+
+	var users []*User
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &users)
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (m UserModel) GetUsersByIDCodes(idCodes []int64) ([]*User, error) {
+	var idCodeExpressions []postgres.Expression
+	for _, c := range idCodes {
+		idCodeExpressions = append(idCodeExpressions, postgres.Int64(c))
+	}
+
+	if len(idCodeExpressions) == 0 {
+		return nil, nil
+	}
+
+	query := postgres.SELECT(table.Users.ID, table.Users.IDCode).
+		FROM(table.Users).
+		WHERE(table.Users.IDCode.IN(idCodeExpressions...))
+
+	var users []*User
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &users)
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// ImportUsers inserts the users, their class memberships and invitations and links parents to children
+// in one transaction, so either everything is imported or nothing is. invitations[i] is for users[i].
+func (m UserModel) ImportUsers(users []*User, invitations []*Invitation, links []ParentLink) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, u := range users {
+		stmt := table.Users.INSERT(table.Users.MutableColumns.
+			Except(table.Users.CreatedAt, table.Users.Active, table.Users.Archived)).
+			MODEL(u).
+			RETURNING(table.Users.ID)
+
+		var id []int
+
+		err = stmt.QueryContext(ctx, tx, &id)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+				if strings.Contains(pgErr.Message, "email") {
+					return ErrEmailAlreadyExists
+				} else if strings.Contains(pgErr.Message, "id_code") {
+					return ErrIDCodeAlreadyExists
+				}
+			}
+			return err
+		}
+
+		u.ID = id[0]
+
+		if *u.Role == RoleStudent && u.ClassID != nil {
+			err = insertCurrentMembership(ctx, tx, u.ID, *u.ClassID)
+			if err != nil {
+				return err
+			}
+		}
+
+		invitations[i].UserID = &u.ID
+
+		err = insertInvitation(ctx, tx, invitations[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, l := range links {
+		stmt := table.ParentsChildren.INSERT(table.ParentsChildren.AllColumns).
+			MODEL(model.ParentsChildren{
+				ParentID: &l.Parent.ID,
+				ChildID:  &l.Child.ID,
+			}).
+			ON_CONFLICT(table.ParentsChildren.AllColumns...).DO_NOTHING()
+
+		_, err = stmt.ExecContext(ctx, tx)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -13,7 +13,10 @@ import (
 	"github.com/go-jet/jet/v2/qrm"
 )
 
-var ErrNoCurrentYear = errors.New("no current year set")
+var (
+	ErrNoCurrentYear = errors.New("no current year set")
+	ErrNoSuchYear    = errors.New("no such year")
+)
 
 type Year = model.Years
 
@@ -56,10 +59,9 @@ func (m YearModel) ListAllYearsWithStats() ([]*YearExt, error) {
 		FROM(table.Journals).
 		WHERE(table.Journals.YearID.EQ(table.Years.ID))
 
-	studentCount := postgres.SELECT(postgres.COUNT(helpers.PostgresInt(1))).
-		FROM(table.Users.
-			INNER_JOIN(table.ClassesYears, table.ClassesYears.YearID.EQ(table.Years.ID))).
-		WHERE(table.Users.ClassID.EQ(table.ClassesYears.ClassID))
+	studentCount := postgres.SELECT(postgres.COUNT(postgres.DISTINCT(table.ClassMemberships.StudentID))).
+		FROM(table.ClassMemberships).
+		WHERE(table.ClassMemberships.YearID.EQ(table.Years.ID))
 
 	query := postgres.SELECT(table.Years.AllColumns, journalCount.AS("stats.journal_count"), studentCount.AS("stats.student_count")).
 		FROM(table.Years)
@@ -94,21 +96,6 @@ func (m YearModel) GetAllYearIDs() ([]int, error) {
 	return ids, nil
 }
 
-func (m YearModel) InsertYear(y *Year) error {
-	stmt := table.Years.INSERT(table.Years.MutableColumns).
-		MODEL(y).RETURNING(table.Years.ID, table.Years.DisplayName)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	err := stmt.QueryContext(ctx, m.DB, y)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
 func (m YearModel) InsertYearForClass(cy *model.ClassesYears) error {
 	stmt := table.ClassesYears.INSERT(table.ClassesYears.AllColumns).
 		MODEL(cy).
@@ -169,14 +156,40 @@ func (m YearModel) GetCurrentYear() (*YearExt, error) {
 	return &year, nil
 }
 
-func (m YearModel) GetYearsForStudent(studentID int) ([]*YearExt, error) {
+func (m YearModel) GetYearByID(yearID int) (*Year, error) {
+	query := postgres.SELECT(table.Years.AllColumns).
+		FROM(table.Years).
+		WHERE(table.Years.ID.EQ(helpers.PostgresInt(yearID)))
 
-	query := postgres.SELECT(table.Years.ID, table.Years.DisplayName, table.Years.Current, table.ClassesYears.DisplayName).DISTINCT().
-		FROM(table.Years.
-			INNER_JOIN(table.ClassesYears, table.ClassesYears.YearID.EQ(table.Years.ID)).
-			INNER_JOIN(table.Classes, table.Classes.ID.EQ(table.ClassesYears.ClassID)).
-			INNER_JOIN(table.Users, table.Users.ClassID.EQ(table.ClassesYears.ClassID))).
-		WHERE(table.Users.ID.EQ(helpers.PostgresInt(studentID)))
+	var year Year
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := query.QueryContext(ctx, m.DB, &year)
+	if err != nil {
+		switch {
+		case errors.Is(err, qrm.ErrNoRows):
+			return nil, ErrNoSuchYear
+		default:
+			return nil, err
+		}
+	}
+
+	return &year, nil
+}
+
+// GetYearsForStudent returns the years the student has been in a class,
+// with the name of the class they were in last during each year
+func (m YearModel) GetYearsForStudent(studentID int) ([]*YearExt, error) {
+	query := postgres.SELECT(table.Years.ID, table.Years.DisplayName, table.Years.Current, table.ClassesYears.DisplayName).
+		DISTINCT(table.Years.ID).
+		FROM(table.ClassMemberships.
+			INNER_JOIN(table.Years, table.Years.ID.EQ(table.ClassMemberships.YearID)).
+			LEFT_JOIN(table.ClassesYears, table.ClassesYears.ClassID.EQ(table.ClassMemberships.ClassID).
+				AND(table.ClassesYears.YearID.EQ(table.ClassMemberships.YearID)))).
+		WHERE(table.ClassMemberships.StudentID.EQ(helpers.PostgresInt(studentID))).
+		ORDER_BY(table.Years.ID.ASC(), table.ClassMemberships.EndDate.DESC())
 
 	var years []*YearExt
 
@@ -214,35 +227,3 @@ func (m YearModel) GetYearsForClass(classID int) ([]*YearExt, error) {
 
 	return cy, nil
 }
-
-func (m YearModel) RemoveCurrentYear() error {
-	stmt := table.Years.UPDATE(table.Years.Current).
-		SET(postgres.Bool(false)).
-		WHERE(table.Years.Current.IS_TRUE())
-
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	_, err := stmt.ExecContext(ctx, m.DB)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func (m YearModel) SetYearAsCurrent(yearID int) error {
-	stmt := table.Years.UPDATE(table.Years.Current).
-		SET(postgres.Bool(true)).
-		WHERE(table.Years.ID.EQ(helpers.PostgresInt(yearID)))
-
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	_, err := stmt.ExecContext(ctx, m.DB)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
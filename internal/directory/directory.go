@@ -0,0 +1,170 @@
+package directory
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrNoSuchEntry        = errors.New("no such directory entry")
+)
+
+type Config struct {
+	URL                string
+	StartTLS           bool
+	InsecureSkipVerify bool
+	BindDN             string
+	BindPassword       string
+	BaseDN             string
+	// UserFilter finds a user by email, %s is replaced by the escaped email
+	UserFilter string
+	// SyncFilter finds the users that are synced
+	SyncFilter     string
+	NameAttribute  string
+	EmailAttribute string
+	RoleAttribute  string
+	// Roles maps values of RoleAttribute to lavurso roles
+	Roles       map[string]string
+	DefaultRole string
+}
+
+type Entry struct {
+	DN    string
+	Name  string
+	Email string
+	Role  string
+}
+
+type Directory struct {
+	Config Config
+}
+
+// connect dials the server and binds as the service account
+func (d *Directory) connect() (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: d.Config.InsecureSkipVerify}
+
+	conn, err := ldap.DialURL(d.Config.URL, ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Config.StartTLS {
+		err = conn.StartTLS(tlsConfig)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	if d.Config.BindDN != "" {
+		err = conn.Bind(d.Config.BindDN, d.Config.BindPassword)
+	} else {
+		err = conn.UnauthenticatedBind("")
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+func (d *Directory) attributes() []string {
+	attributes := []string{d.Config.NameAttribute, d.Config.EmailAttribute}
+	if d.Config.RoleAttribute != "" {
+		attributes = append(attributes, d.Config.RoleAttribute)
+	}
+	return attributes
+}
+
+func (d *Directory) toEntry(e *ldap.Entry) *Entry {
+	entry := &Entry{
+		DN:    e.DN,
+		Name:  e.GetAttributeValue(d.Config.NameAttribute),
+		Email: e.GetAttributeValue(d.Config.EmailAttribute),
+		Role:  d.Config.DefaultRole,
+	}
+
+	if d.Config.RoleAttribute != "" {
+		for _, value := range e.GetAttributeValues(d.Config.RoleAttribute) {
+			if role, ok := d.Config.Roles[value]; ok {
+				entry.Role = role
+				break
+			}
+		}
+	}
+
+	return entry
+}
+
+// Authenticate finds the user by email and binds as them with the password
+func (d *Directory) Authenticate(email, password string) (*Entry, error) {
+	// an empty password would make an unauthenticated bind, which always succeeds
+	if password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		d.Config.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 0, false,
+		fmt.Sprintf(d.Config.UserFilter, ldap.EscapeFilter(email)),
+		d.attributes(),
+		nil,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Entries) != 1 {
+		return nil, ErrNoSuchEntry
+	}
+
+	entry := d.toEntry(result.Entries[0])
+
+	err = conn.Bind(entry.DN, password)
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// SyncEntries returns all users matching the sync filter
+func (d *Directory) SyncEntries() ([]*Entry, error) {
+	conn, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	result, err := conn.SearchWithPaging(ldap.NewSearchRequest(
+		d.Config.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		d.Config.SyncFilter,
+		d.attributes(),
+		nil,
+	), 500)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	for _, e := range result.Entries {
+		entries = append(entries, d.toEntry(e))
+	}
+
+	return entries, nil
+}
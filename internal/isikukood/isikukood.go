@@ -0,0 +1,94 @@
+// Package isikukood validates Estonian personal ID codes.
+//
+// A code has 11 digits in the form GYYMMDDSSSC, where G encodes the sex and
+// century of birth, YYMMDD is the birth date, SSS is a serial number and C is
+// the check digit.
+package isikukood
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidLength    = errors.New("must be 11 digits long")
+	ErrInvalidCentury   = errors.New("must start with a digit between 1 and 8")
+	ErrInvalidBirthDate = errors.New("must contain a valid birth date")
+	ErrInvalidChecksum  = errors.New("must have a valid check digit")
+)
+
+var (
+	weights1 = [10]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 1}
+	weights2 = [10]int{3, 4, 5, 6, 7, 8, 9, 1, 2, 3}
+)
+
+// digits returns the code's digits, ok is false if it isn't 11 digits long
+func digits(code int64) ([11]int, bool) {
+	var d [11]int
+
+	if code < 1e10 || code >= 1e11 {
+		return d, false
+	}
+
+	for i := 10; i >= 0; i-- {
+		d[i] = int(code % 10)
+		code /= 10
+	}
+
+	return d, true
+}
+
+// checkDigit calculates the last digit from the first ten
+func checkDigit(d [11]int) int {
+	var sum int
+	for i, w := range weights1 {
+		sum += d[i] * w
+	}
+	if sum%11 < 10 {
+		return sum % 11
+	}
+
+	sum = 0
+	for i, w := range weights2 {
+		sum += d[i] * w
+	}
+	if sum%11 < 10 {
+		return sum % 11
+	}
+
+	return 0
+}
+
+// BirthDate validates the code and returns the birth date encoded in it
+func BirthDate(code int64) (time.Time, error) {
+	d, ok := digits(code)
+	if !ok {
+		return time.Time{}, ErrInvalidLength
+	}
+
+	if d[0] < 1 || d[0] > 8 {
+		return time.Time{}, ErrInvalidCentury
+	}
+
+	year := 1800 + (d[0]-1)/2*100 + d[1]*10 + d[2]
+	month := time.Month(d[3]*10 + d[4])
+	day := d[5]*10 + d[6]
+
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	// time.Date normalizes dates like February 30th
+	if date.Month() != month || date.Day() != day {
+		return time.Time{}, ErrInvalidBirthDate
+	}
+
+	if checkDigit(d) != d[10] {
+		return time.Time{}, ErrInvalidChecksum
+	}
+
+	return date, nil
+}
+
+// Valid reports whether the code is a valid personal ID code
+func Valid(code int64) bool {
+	_, err := BirthDate(code)
+	return err == nil
+}
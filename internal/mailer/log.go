@@ -0,0 +1,16 @@
+package mailer
+
+import (
+	"log"
+)
+
+// LogMailer writes messages to a logger instead of sending them,
+// meant for development and testing
+type LogMailer struct {
+	Logger *log.Logger
+}
+
+func (m *LogMailer) Send(msg *Message) error {
+	m.Logger.Printf("to: %s, subject: %s\n%s\n", msg.To, msg.Subject, msg.Body)
+	return nil
+}
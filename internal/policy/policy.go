@@ -0,0 +1,234 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type Permission string
+
+const (
+	UserList   Permission = "user.list"
+	UserView   Permission = "user.view"
+	UserSearch Permission = "user.search"
+	UserManage Permission = "user.manage"
+
+	UserImpersonate Permission = "user.impersonate"
+
+	SessionManage Permission = "session.manage"
+	SessionRevoke Permission = "session.revoke"
+
+	LogView       Permission = "log.view"
+	LockoutManage Permission = "lockout.manage"
+
+	ClassList         Permission = "class.list"
+	ClassManage       Permission = "class.manage"
+	ClassViewStudents Permission = "class.view_students"
+
+	SubjectView   Permission = "subject.view"
+	SubjectManage Permission = "subject.manage"
+
+	GradeView   Permission = "grade.view"
+	GradeManage Permission = "grade.manage"
+
+	GroupView   Permission = "group.view"
+	GroupManage Permission = "group.manage"
+
+	TeacherView Permission = "teacher.view"
+
+	JournalList      Permission = "journal.list"
+	JournalCreate    Permission = "journal.create"
+	JournalView      Permission = "journal.view"
+	JournalEdit      Permission = "journal.edit"
+	JournalEditMarks Permission = "journal.edit_marks"
+	JournalDelete    Permission = "journal.delete"
+
+	StudentView        Permission = "student.view"
+	StudentViewProfile Permission = "student.view_profile"
+	StudentExcuse      Permission = "student.excuse"
+
+	AssignmentMarkDone Permission = "assignment.mark_done"
+
+	YearView   Permission = "year.view"
+	YearManage Permission = "year.manage"
+
+	ThreadCreate          Permission = "thread.create"
+	ThreadView            Permission = "thread.view"
+	ThreadLock            Permission = "thread.lock"
+	ThreadDelete          Permission = "thread.delete"
+	ThreadManageMembers   Permission = "thread.manage_members"
+	ThreadMessageAnyGroup Permission = "thread.message_any_group"
+
+	MessageCreate Permission = "message.create"
+	MessageEdit   Permission = "message.edit"
+)
+
+var Permissions = []Permission{
+	UserList, UserView, UserSearch, UserManage,
+	UserImpersonate,
+	SessionManage, SessionRevoke,
+	LogView, LockoutManage,
+	ClassList, ClassManage, ClassViewStudents,
+	SubjectView, SubjectManage,
+	GradeView, GradeManage,
+	GroupView, GroupManage,
+	TeacherView,
+	JournalList, JournalCreate, JournalView, JournalEdit, JournalEditMarks, JournalDelete,
+	StudentView, StudentViewProfile, StudentExcuse,
+	AssignmentMarkDone,
+	YearView, YearManage,
+	ThreadCreate, ThreadView, ThreadLock, ThreadDelete, ThreadManageMembers, ThreadMessageAnyGroup,
+	MessageCreate, MessageEdit,
+}
+
+// RelatedSuffix limits a granted permission to resources the user is related to,
+// e.g. a teacher's own journals or a parent's children
+const RelatedSuffix = ":related"
+
+type Decision int
+
+const (
+	Denied Decision = iota
+	AllowedIfRelated
+	Allowed
+)
+
+// DefaultRoles is used when no roles are configured
+var DefaultRoles = map[string][]string{
+	"admin": {
+		"user.list", "user.view", "user.search", "user.manage",
+		"user.impersonate",
+		"session.manage", "session.revoke",
+		"log.view", "lockout.manage",
+		"class.list", "class.manage", "class.view_students",
+		"subject.view", "subject.manage",
+		"grade.view", "grade.manage",
+		"group.view", "group.manage",
+		"teacher.view",
+		"journal.list", "journal.create", "journal.view", "journal.edit", "journal.edit_marks", "journal.delete",
+		"student.view", "student.view_profile", "student.excuse",
+		"year.view", "year.manage",
+		"thread.create", "thread.view:related", "thread.lock:related", "thread.delete:related", "thread.manage_members:related", "thread.message_any_group",
+		"message.create:related", "message.edit:related",
+	},
+	"teacher": {
+		"user.view:related", "user.search",
+		"session.revoke:related",
+		"class.list", "class.view_students:related",
+		"subject.view",
+		"grade.view",
+		"group.view:related",
+		"teacher.view:related",
+		"journal.create", "journal.view:related", "journal.edit:related", "journal.edit_marks:related",
+		"student.view:related", "student.view_profile:related", "student.excuse:related",
+		"year.view",
+		"thread.create", "thread.view:related", "thread.lock:related", "thread.delete:related", "thread.manage_members:related", "thread.message_any_group",
+		"message.create:related", "message.edit:related",
+	},
+	"parent": {
+		"user.view:related", "user.search",
+		"session.revoke:related",
+		"group.view:related",
+		"student.view:related", "student.excuse:related",
+		"year.view",
+		"thread.create", "thread.view:related", "thread.lock:related", "thread.delete:related", "thread.manage_members:related",
+		"message.create:related", "message.edit:related",
+	},
+	"student": {
+		"user.view:related", "user.search",
+		"session.revoke:related",
+		"group.view:related",
+		"student.view:related",
+		"assignment.mark_done:related",
+		"year.view",
+		"thread.create", "thread.view:related", "thread.lock:related", "thread.delete:related", "thread.manage_members:related",
+		"message.create:related", "message.edit:related",
+	},
+}
+
+// Policy maps roles to the permissions granted to them
+type Policy struct {
+	roles map[string]map[Permission]Decision
+}
+
+// New parses role to permission mappings, where every permission
+// is either granted fully or only for related resources with RelatedSuffix
+func New(roles map[string][]string) (*Policy, error) {
+	known := make(map[Permission]bool)
+	for _, p := range Permissions {
+		known[p] = true
+	}
+
+	p := &Policy{roles: make(map[string]map[Permission]Decision)}
+
+	for role, permissions := range roles {
+		granted := make(map[Permission]Decision)
+
+		for _, s := range permissions {
+			decision := Allowed
+			if strings.HasSuffix(s, RelatedSuffix) {
+				s = strings.TrimSuffix(s, RelatedSuffix)
+				decision = AllowedIfRelated
+			}
+
+			if !known[Permission(s)] {
+				return nil, fmt.Errorf("role %q: unknown permission %q", role, s)
+			}
+
+			if decision > granted[Permission(s)] {
+				granted[Permission(s)] = decision
+			}
+		}
+
+		p.roles[role] = granted
+	}
+
+	return p, nil
+}
+
+func (p *Policy) HasRole(role string) bool {
+	_, ok := p.roles[role]
+	return ok
+}
+
+func (p *Policy) Roles() []string {
+	var roles []string
+	for role := range p.roles {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// Check returns the broadest decision any of the roles has for the permission
+func (p *Policy) Check(roles []string, permission Permission) Decision {
+	decision := Denied
+	for _, role := range roles {
+		if d := p.roles[role][permission]; d > decision {
+			decision = d
+		}
+	}
+	return decision
+}
+
+// Allowed reports whether any of the roles has the permission for all resources
+func (p *Policy) Allowed(roles []string, permission Permission) bool {
+	return p.Check(roles, permission) == Allowed
+}
+
+// Authorize reports whether the roles have the permission for a resource.
+// related is only called if the roles have the permission for related resources only.
+func (p *Policy) Authorize(roles []string, permission Permission, related func() (bool, error)) (bool, error) {
+	switch p.Check(roles, permission) {
+	case Allowed:
+		return true, nil
+	case AllowedIfRelated:
+		if related == nil {
+			return false, nil
+		}
+		return related()
+	default:
+		return false, nil
+	}
+}
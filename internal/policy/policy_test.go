@@ -0,0 +1,124 @@
+package policy
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		roles   map[string][]string
+		wantErr bool
+	}{
+		{"default roles", DefaultRoles, false},
+		{"no roles", map[string][]string{}, false},
+		{"known permissions", map[string][]string{"teacher": {"journal.view", "journal.edit:related"}}, false},
+		{"unknown permission", map[string][]string{"teacher": {"journal.fly"}}, true},
+		{"unknown related permission", map[string][]string{"teacher": {"journal.fly:related"}}, true},
+		{"only suffix", map[string][]string{"teacher": {":related"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.roles)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	p, err := New(map[string][]string{
+		"teacher": {"journal.view:related", "class.list", "student.view:related"},
+		"parent":  {"student.view:related", "student.excuse:related"},
+		"admin":   {"journal.view", "journal.view:related"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		roles      []string
+		permission Permission
+		want       Decision
+	}{
+		{"granted", []string{"teacher"}, ClassList, Allowed},
+		{"related suffix", []string{"teacher"}, JournalView, AllowedIfRelated},
+		{"not granted", []string{"parent"}, ClassList, Denied},
+		{"unknown role", []string{"janitor"}, ClassList, Denied},
+		{"no roles", nil, ClassList, Denied},
+		{"full grant wins within a role", []string{"admin"}, JournalView, Allowed},
+		{"teacher and parent, only teacher granted", []string{"teacher", "parent"}, ClassList, Allowed},
+		{"teacher and parent, only parent granted", []string{"teacher", "parent"}, StudentExcuse, AllowedIfRelated},
+		{"teacher and parent, both related", []string{"parent", "teacher"}, StudentView, AllowedIfRelated},
+		{"broadest role wins", []string{"teacher", "admin"}, JournalView, Allowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.Check(tt.roles, tt.permission); got != tt.want {
+				t.Errorf("Check() = %v, want %v", got, tt.want)
+			}
+			if got := p.Allowed(tt.roles, tt.permission); got != (tt.want == Allowed) {
+				t.Errorf("Allowed() = %v, want %v", got, tt.want == Allowed)
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	p, err := New(map[string][]string{
+		"teacher": {"journal.view:related", "class.list"},
+		"parent":  {"student.view:related"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errRelated := errors.New("related failed")
+
+	tests := []struct {
+		name        string
+		roles       []string
+		permission  Permission
+		related     func() (bool, error)
+		want        bool
+		wantErr     error
+		wantRelated bool
+	}{
+		{"allowed", []string{"teacher"}, ClassList, func() (bool, error) { return false, nil }, true, nil, false},
+		{"denied", []string{"parent"}, ClassList, func() (bool, error) { return true, nil }, false, nil, false},
+		{"related", []string{"teacher"}, JournalView, func() (bool, error) { return true, nil }, true, nil, true},
+		{"not related", []string{"teacher"}, JournalView, func() (bool, error) { return false, nil }, false, nil, true},
+		{"related error", []string{"teacher"}, JournalView, func() (bool, error) { return false, errRelated }, false, errRelated, true},
+		{"related without check", []string{"teacher"}, JournalView, nil, false, nil, false},
+		{"teacher and parent", []string{"parent", "teacher"}, ClassList, func() (bool, error) { return false, nil }, true, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			related := tt.related
+			if related != nil {
+				related = func() (bool, error) {
+					called = true
+					return tt.related()
+				}
+			}
+
+			got, err := p.Authorize(tt.roles, tt.permission, related)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Authorize() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Authorize() = %v, want %v", got, tt.want)
+			}
+			if called != tt.wantRelated {
+				t.Errorf("related called = %v, want %v", called, tt.wantRelated)
+			}
+		})
+	}
+}
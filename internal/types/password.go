@@ -1,18 +1,50 @@
 package types
 
 import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
 	"database/sql/driver"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"strings"
 
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// parameters for new argon2id hashes, hashes made with
+// other parameters or with bcrypt need rehashing
+const (
+	argon2Time       = 3
+	argon2Memory     = 64 * 1024
+	argon2Threads    = 4
+	argon2KeyLength  = 32
+	argon2SaltLength = 16
+)
+
+var argon2Prefix = []byte("$argon2id$")
+
+// disabledHash can't be produced by any password
+var disabledHash = []byte("!")
+
+var ErrInvalidHash = errors.New("invalid password hash")
+
 type Password struct {
 	Hashed    []byte
 	Plaintext string
 }
 
 func (p Password) Validate(check string) (bool, error) {
+	if bytes.Equal(p.Hashed, disabledHash) {
+		return false, nil
+	}
+
+	if bytes.HasPrefix(p.Hashed, argon2Prefix) {
+		return p.validateArgon2(check)
+	}
+
 	err := bcrypt.CompareHashAndPassword(p.Hashed, []byte(check))
 	if err != nil {
 		switch {
@@ -26,16 +58,95 @@ func (p Password) Validate(check string) (bool, error) {
 	return true, nil
 }
 
+// CreateHash hashes the plaintext password with argon2id,
+// encoded like $argon2id$v=19$m=65536,t=3,p=4$salt$key
 func (p *Password) CreateHash() error {
-	hashed, err := bcrypt.GenerateFromPassword([]byte(p.Plaintext), 12)
+	salt := make([]byte, argon2SaltLength)
+	_, err := rand.Read(salt)
 	if err != nil {
 		return err
 	}
-	p.Hashed = hashed
+
+	key := argon2.IDKey([]byte(p.Plaintext), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLength)
+
+	p.Hashed = []byte(fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, argon2Memory, argon2Time, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)))
 
 	return nil
 }
 
+// Disable sets a hash that no password matches, for users who have to
+// choose their password before they can log in with one
+func (p *Password) Disable() {
+	p.Hashed = disabledHash
+}
+
+// NeedsRehash reports whether the hash was made with bcrypt
+// or with other argon2id parameters than new hashes are
+func (p Password) NeedsRehash() bool {
+	if !bytes.HasPrefix(p.Hashed, argon2Prefix) {
+		return true
+	}
+
+	params, _, _, err := decodeArgon2(p.Hashed)
+	if err != nil {
+		return true
+	}
+
+	return params != argon2Params{argon2Time, argon2Memory, argon2Threads}
+}
+
+func (p Password) validateArgon2(check string) (bool, error) {
+	params, salt, key, err := decodeArgon2(p.Hashed)
+	if err != nil {
+		return false, err
+	}
+
+	checkKey := argon2.IDKey([]byte(check), salt, params.time, params.memory, params.threads, uint32(len(key)))
+
+	return subtle.ConstantTimeCompare(key, checkKey) == 1, nil
+}
+
+type argon2Params struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+}
+
+func decodeArgon2(hash []byte) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+
+	parts := strings.Split(string(hash), "$")
+	if len(parts) != 6 {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads)
+	if err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	return params, salt, key, nil
+}
+
 func (p *Password) Scan(src any) error {
 	p.Hashed = src.([]byte)
 	return nil
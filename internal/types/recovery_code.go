@@ -0,0 +1,29 @@
+package types
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"strings"
+)
+
+// GenerateRecoveryCode returns a random code in the form xxxxx-xxxxx
+func GenerateRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	s := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))
+	return s[:5] + "-" + s[5:10], nil
+}
+
+// HashRecoveryCode hashes the code, ignoring case, spaces and dashes
+// so codes typed in by hand still match
+func HashRecoveryCode(code string) []byte {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	hash := sha256.Sum256([]byte(code))
+	return hash[:]
+}
@@ -0,0 +1,35 @@
+package types
+
+import (
+	"database/sql/driver"
+	"strings"
+)
+
+// Roles are all roles of a user, stored space-separated like Scopes
+type Roles []string
+
+func (r Roles) Has(role string) bool {
+	for _, ro := range r {
+		if ro == role {
+			return true
+		}
+	}
+	return false
+}
+
+// With returns the roles with role added, if it isn't there already
+func (r Roles) With(role string) Roles {
+	if r.Has(role) {
+		return r
+	}
+	return append(r, role)
+}
+
+func (r *Roles) Scan(src any) error {
+	*r = strings.Fields(src.(string))
+	return nil
+}
+
+func (r Roles) Value() (driver.Value, error) {
+	return strings.Join(r, " "), nil
+}
@@ -0,0 +1,27 @@
+package types
+
+import (
+	"database/sql/driver"
+	"strings"
+)
+
+// Scopes are stored space-separated, like OAuth scopes
+type Scopes []string
+
+func (s Scopes) Has(scope string) bool {
+	for _, sc := range s {
+		if sc == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scopes) Scan(src any) error {
+	*s = strings.Fields(src.(string))
+	return nil
+}
+
+func (s Scopes) Value() (driver.Value, error) {
+	return strings.Join(s, " "), nil
+}
@@ -4,11 +4,23 @@ import (
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha1"
+	"crypto/subtle"
 	"encoding/base32"
 	"encoding/binary"
+	"math"
+	"net/url"
+	"strconv"
 	"time"
 )
 
+const (
+	totpPeriod = 30
+	totpDigits = 6
+)
+
+// codes are the last totpDigits digits of the truncated hash
+var totpModulus = uint32(math.Pow10(totpDigits))
+
 type TOTPSecret string
 
 func GenerateSecret() (TOTPSecret, error) {
@@ -23,21 +35,59 @@ func GenerateSecret() (TOTPSecret, error) {
 	return TOTPSecret(s), nil
 }
 
-func (secret *TOTPSecret) Validate(otp int) (bool, error) {
-	key, err := base32.StdEncoding.DecodeString(string(*secret))
-	if err != nil {
-		return false, err
-	}
-
-	timestamp := time.Now().Unix() / 30
+func totpCode(key []byte, step int64) int {
 	hm := hmac.New(sha1.New, key)
-	binary.Write(hm, binary.BigEndian, timestamp)
+	binary.Write(hm, binary.BigEndian, step)
 
 	h := hm.Sum(nil)
 	offs := h[len(h)-1] & 0xF
 	truncatedHash := binary.BigEndian.Uint32(h[offs : offs+4])
 
-	code := (truncatedHash & 0x7FFFFFFF) % 1000000
+	return int((truncatedHash & 0x7FFFFFFF) % totpModulus)
+}
+
+// Validate checks the otp against the current time step and skew steps
+// before and after it. Steps not after lastStep are rejected, so a code
+// can't be used twice. On success, the matching step is returned.
+func (secret *TOTPSecret) Validate(otp, skew int, lastStep *int64) (int64, bool, error) {
+	key, err := base32.StdEncoding.DecodeString(string(*secret))
+	if err != nil {
+		return 0, false, err
+	}
+
+	if otp < 0 || otp >= int(totpModulus) {
+		return 0, false, nil
+	}
+
+	current := time.Now().Unix() / totpPeriod
+
+	for step := current - int64(skew); step <= current+int64(skew); step++ {
+		if lastStep != nil && step <= *lastStep {
+			continue
+		}
+		if subtle.ConstantTimeEq(int32(totpCode(key, step)), int32(otp)) == 1 {
+			return step, true, nil
+		}
+	}
+
+	return 0, false, nil
+}
+
+// URI returns the otpauth:// provisioning URI for authenticator apps
+func (secret TOTPSecret) URI(issuer, accountName string) string {
+	query := url.Values{}
+	query.Set("secret", string(secret))
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", strconv.Itoa(totpDigits))
+	query.Set("period", strconv.Itoa(totpPeriod))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + accountName,
+		RawQuery: query.Encode(),
+	}
 
-	return otp == int(code), nil
+	return u.String()
 }
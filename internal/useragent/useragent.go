@@ -0,0 +1,95 @@
+package useragent
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Agent describes the browser and device a User-Agent header comes from
+type Agent struct {
+	Browser        string `json:"browser"`
+	BrowserVersion string `json:"browser_version"`
+	OS             string `json:"os"`
+	Device         string `json:"device"`
+}
+
+type rule struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// browsers are matched in order, since most browsers also claim to be others,
+// e.g. Edge includes Chrome and Safari in its User-Agent
+var browsers = []rule{
+	{"Edge", regexp.MustCompile(`Edg(?:e|A|iOS)?/(\d+)`)},
+	{"Opera", regexp.MustCompile(`(?:OPR|Opera)/(\d+)`)},
+	{"Samsung Internet", regexp.MustCompile(`SamsungBrowser/(\d+)`)},
+	{"Firefox", regexp.MustCompile(`(?:Firefox|FxiOS)/(\d+)`)},
+	{"Chrome", regexp.MustCompile(`(?:Chrome|CriOS)/(\d+)`)},
+	{"Safari", regexp.MustCompile(`Version/(\d+).*Safari/`)},
+	{"curl", regexp.MustCompile(`^curl/(\d+)`)},
+}
+
+var operatingSystems = []rule{
+	{"iPadOS", regexp.MustCompile(`iPad.*OS (\d+)`)},
+	{"iOS", regexp.MustCompile(`iPhone.*OS (\d+)`)},
+	{"Android", regexp.MustCompile(`Android (\d+)`)},
+	{"Windows", regexp.MustCompile(`Windows NT (\d+)`)},
+	{"ChromeOS", regexp.MustCompile(`CrOS \S+ (\d+)`)},
+	{"macOS", regexp.MustCompile(`Mac OS X (\d+)`)},
+	{"Linux", regexp.MustCompile(`Linux()`)},
+}
+
+func match(rules []rule, ua string) (string, string) {
+	for _, r := range rules {
+		m := r.pattern.FindStringSubmatch(ua)
+		if m != nil {
+			return r.name, m[1]
+		}
+	}
+	return "", ""
+}
+
+// Parse makes a best effort at recognizing common browsers and operating systems,
+// unknown ones are left empty
+func Parse(ua string) Agent {
+	var agent Agent
+
+	agent.Browser, agent.BrowserVersion = match(browsers, ua)
+	agent.OS, _ = match(operatingSystems, ua)
+
+	switch {
+	case strings.Contains(ua, "iPad") || strings.Contains(ua, "Tablet"):
+		agent.Device = "tablet"
+	case strings.Contains(ua, "Mobi") || strings.Contains(ua, "iPhone"):
+		agent.Device = "phone"
+	case agent.OS == "Android":
+		agent.Device = "tablet"
+	case agent.OS != "":
+		agent.Device = "computer"
+	}
+
+	return agent
+}
+
+// Description is a short human readable description, e.g. "Firefox 118 on Windows"
+func (a Agent) Description() string {
+	browser := strings.TrimSpace(a.Browser + " " + a.BrowserVersion)
+
+	switch {
+	case browser != "" && a.OS != "":
+		return browser + " on " + a.OS
+	case browser != "":
+		return browser
+	case a.OS != "":
+		return a.OS
+	default:
+		return "unknown device"
+	}
+}
+
+// SameDevice reports whether two agents look like the same browser on the same system,
+// ignoring browser versions so that updates don't count as new devices
+func (a Agent) SameDevice(b Agent) bool {
+	return a.Browser == b.Browser && a.OS == b.OS && a.Device == b.Device
+}
@@ -0,0 +1,15 @@
+CREATE TABLE "password_resets" (
+    "id" integer GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    "user_id" integer NOT NULL,
+    "token" bytea UNIQUE NOT NULL,
+    "expires" timestamptz NOT NULL,
+    "used_at" timestamptz,
+    "created_at" timestamptz NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE "password_resets"
+    ADD CONSTRAINT "password_resets_relation_1" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE CASCADE ON DELETE CASCADE;
+
+---- create above / drop below ----
+
+DROP TABLE "password_resets";
\ No newline at end of file
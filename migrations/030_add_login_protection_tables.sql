@@ -0,0 +1,36 @@
+CREATE TABLE "failed_logins" (
+    "id" bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    "user_id" integer,
+    "ip" text NOT NULL,
+    "at" timestamptz NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE "failed_logins"
+    ADD CONSTRAINT "failed_logins_relation_1" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE CASCADE ON DELETE CASCADE;
+
+CREATE INDEX failed_logins_user_id_at_idx ON failed_logins (user_id, at);
+
+CREATE INDEX failed_logins_ip_at_idx ON failed_logins (ip, at);
+
+CREATE TABLE "lockouts" (
+    "id" integer GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    "user_id" integer,
+    "ip" text,
+    "locked_until" timestamptz NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT NOW(),
+    "cleared_by" integer,
+    "cleared_at" timestamptz,
+    CHECK (user_id IS NOT NULL OR ip IS NOT NULL)
+);
+
+ALTER TABLE "lockouts"
+    ADD CONSTRAINT "lockouts_relation_1" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE "lockouts"
+    ADD CONSTRAINT "lockouts_relation_2" FOREIGN KEY ("cleared_by") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION;
+
+---- create above / drop below ----
+
+DROP TABLE "lockouts";
+
+DROP TABLE "failed_logins";
\ No newline at end of file
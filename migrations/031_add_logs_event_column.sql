@@ -0,0 +1,5 @@
+ALTER TABLE "logs" ADD "event" text;
+
+---- create above / drop below ----
+
+ALTER TABLE "logs" DROP "event";
\ No newline at end of file
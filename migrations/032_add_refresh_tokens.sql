@@ -0,0 +1,38 @@
+CREATE TABLE "session_families" (
+    "id" integer GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    "user_id" integer NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT NOW(),
+    "revoked_at" timestamptz
+);
+
+ALTER TABLE "session_families"
+    ADD CONSTRAINT "session_families_relation_1" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE "sessions" ADD "family_id" integer;
+
+ALTER TABLE "sessions"
+    ADD CONSTRAINT "sessions_relation_2" FOREIGN KEY ("family_id") REFERENCES "session_families" ("id") ON UPDATE CASCADE ON DELETE CASCADE;
+
+CREATE TABLE "refresh_tokens" (
+    "id" integer GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    "token" bytea UNIQUE NOT NULL,
+    "family_id" integer NOT NULL,
+    "session_id" integer NOT NULL,
+    "expires" timestamptz NOT NULL,
+    "used_at" timestamptz,
+    "created_at" timestamptz NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE "refresh_tokens"
+    ADD CONSTRAINT "refresh_tokens_relation_1" FOREIGN KEY ("family_id") REFERENCES "session_families" ("id") ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE "refresh_tokens"
+    ADD CONSTRAINT "refresh_tokens_relation_2" FOREIGN KEY ("session_id") REFERENCES "sessions" ("id") ON UPDATE CASCADE ON DELETE CASCADE;
+
+---- create above / drop below ----
+
+DROP TABLE "refresh_tokens";
+
+ALTER TABLE "sessions" DROP "family_id";
+
+DROP TABLE "session_families";
\ No newline at end of file
@@ -0,0 +1,16 @@
+CREATE TABLE "recovery_codes" (
+    "id" integer GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    "user_id" integer NOT NULL,
+    "code" bytea NOT NULL,
+    "used_at" timestamptz,
+    "created_at" timestamptz NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE "recovery_codes"
+    ADD CONSTRAINT "recovery_codes_relation_1" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE CASCADE ON DELETE CASCADE;
+
+CREATE UNIQUE INDEX "recovery_codes_user_id_code_idx" ON "recovery_codes" ("user_id", "code");
+
+---- create above / drop below ----
+
+DROP TABLE "recovery_codes";
\ No newline at end of file
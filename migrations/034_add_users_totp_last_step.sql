@@ -0,0 +1,5 @@
+ALTER TABLE "users" ADD "totp_last_step" bigint;
+
+---- create above / drop below ----
+
+ALTER TABLE "users" DROP "totp_last_step";
\ No newline at end of file
@@ -0,0 +1,29 @@
+CREATE TABLE "passkeys" (
+    "id" integer GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    "user_id" integer NOT NULL,
+    "name" text NOT NULL,
+    "credential_id" bytea UNIQUE NOT NULL,
+    "credential" jsonb NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT NOW(),
+    "last_used_at" timestamptz
+);
+
+ALTER TABLE "passkeys"
+    ADD CONSTRAINT "passkeys_relation_1" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE CASCADE ON DELETE CASCADE;
+
+CREATE TABLE "passkey_challenges" (
+    "id" integer GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    "challenge" text UNIQUE NOT NULL,
+    "user_id" integer,
+    "session_data" jsonb NOT NULL,
+    "expires" timestamptz NOT NULL
+);
+
+ALTER TABLE "passkey_challenges"
+    ADD CONSTRAINT "passkey_challenges_relation_1" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE CASCADE ON DELETE CASCADE;
+
+---- create above / drop below ----
+
+DROP TABLE "passkey_challenges";
+
+DROP TABLE "passkeys";
\ No newline at end of file
@@ -0,0 +1,28 @@
+CREATE TABLE "external_identities" (
+    "id" integer GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    "user_id" integer NOT NULL,
+    "provider" text NOT NULL,
+    "subject" text NOT NULL,
+    "email" text,
+    "created_at" timestamptz NOT NULL DEFAULT NOW(),
+    "last_login_at" timestamptz,
+    UNIQUE ("provider", "subject")
+);
+
+ALTER TABLE "external_identities"
+    ADD CONSTRAINT "external_identities_relation_1" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE CASCADE ON DELETE CASCADE;
+
+CREATE TABLE "oidc_states" (
+    "id" integer GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    "state" text UNIQUE NOT NULL,
+    "provider" text NOT NULL,
+    "nonce" text NOT NULL,
+    "code_verifier" text NOT NULL,
+    "expires" timestamptz NOT NULL
+);
+
+---- create above / drop below ----
+
+DROP TABLE "oidc_states";
+
+DROP TABLE "external_identities";
\ No newline at end of file
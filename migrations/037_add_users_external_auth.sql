@@ -0,0 +1,5 @@
+ALTER TABLE "users" ADD "external_auth" boolean NOT NULL DEFAULT FALSE;
+
+---- create above / drop below ----
+
+ALTER TABLE "users" DROP "external_auth";
\ No newline at end of file
@@ -0,0 +1,29 @@
+CREATE TABLE "api_tokens" (
+    "id" integer GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    "user_id" integer NOT NULL,
+    "name" text NOT NULL,
+    "token" bytea UNIQUE NOT NULL,
+    "scopes" text NOT NULL,
+    "created_by" integer,
+    "created_at" timestamptz NOT NULL DEFAULT NOW(),
+    "expires" timestamptz NOT NULL,
+    "last_used_at" timestamptz
+);
+
+ALTER TABLE "api_tokens"
+    ADD CONSTRAINT "api_tokens_relation_1" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE "api_tokens"
+    ADD CONSTRAINT "api_tokens_relation_2" FOREIGN KEY ("created_by") REFERENCES "users" ("id") ON UPDATE CASCADE ON DELETE SET NULL;
+
+ALTER TABLE "users" ADD "service_account" boolean NOT NULL DEFAULT FALSE;
+
+ALTER TABLE "logs" ADD "api_token_id" integer;
+
+---- create above / drop below ----
+
+ALTER TABLE "logs" DROP "api_token_id";
+
+ALTER TABLE "users" DROP "service_account";
+
+DROP TABLE "api_tokens";
\ No newline at end of file
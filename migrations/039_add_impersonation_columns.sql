@@ -0,0 +1,12 @@
+ALTER TABLE "sessions" ADD "impersonator_id" integer;
+
+ALTER TABLE "sessions"
+    ADD CONSTRAINT "sessions_relation_3" FOREIGN KEY ("impersonator_id") REFERENCES "users" ("id") ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE "logs" ADD "impersonator_id" integer;
+
+---- create above / drop below ----
+
+ALTER TABLE "logs" DROP "impersonator_id";
+
+ALTER TABLE "sessions" DROP "impersonator_id";
\ No newline at end of file
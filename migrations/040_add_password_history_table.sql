@@ -0,0 +1,13 @@
+CREATE TABLE "password_history" (
+    "id" integer GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    "user_id" integer NOT NULL,
+    "password" bytea NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE "password_history"
+    ADD CONSTRAINT "password_history_relation_1" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE CASCADE ON DELETE CASCADE;
+
+---- create above / drop below ----
+
+DROP TABLE "password_history";
\ No newline at end of file
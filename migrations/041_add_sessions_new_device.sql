@@ -0,0 +1,5 @@
+ALTER TABLE "sessions" ADD "new_device" boolean NOT NULL DEFAULT FALSE;
+
+---- create above / drop below ----
+
+ALTER TABLE "sessions" DROP "new_device";
\ No newline at end of file
@@ -0,0 +1,20 @@
+CREATE TABLE "invitations" (
+    "id" integer GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    "user_id" integer NOT NULL,
+    "token" bytea UNIQUE NOT NULL,
+    "created_by" integer,
+    "created_at" timestamptz NOT NULL DEFAULT NOW(),
+    "expires" timestamptz NOT NULL,
+    "accepted_at" timestamptz,
+    "revoked_at" timestamptz
+);
+
+ALTER TABLE "invitations"
+    ADD CONSTRAINT "invitations_relation_1" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE "invitations"
+    ADD CONSTRAINT "invitations_relation_2" FOREIGN KEY ("created_by") REFERENCES "users" ("id") ON UPDATE CASCADE ON DELETE SET NULL;
+
+---- create above / drop below ----
+
+DROP TABLE "invitations";
\ No newline at end of file
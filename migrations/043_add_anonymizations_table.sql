@@ -0,0 +1,29 @@
+ALTER TABLE "users" ADD "archived_at" timestamptz;
+
+UPDATE "users" SET "archived_at" = NOW() WHERE "archived";
+
+ALTER TABLE "logs" ALTER "ip" DROP NOT NULL;
+
+CREATE TABLE "anonymizations" (
+    "id" integer GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    "user_id" integer UNIQUE NOT NULL,
+    "anonymized_by" integer,
+    "archived_at" timestamptz,
+    "at" timestamptz NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE "anonymizations"
+    ADD CONSTRAINT "anonymizations_relation_1" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE "anonymizations"
+    ADD CONSTRAINT "anonymizations_relation_2" FOREIGN KEY ("anonymized_by") REFERENCES "users" ("id") ON UPDATE CASCADE ON DELETE SET NULL;
+
+---- create above / drop below ----
+
+DROP TABLE "anonymizations";
+
+UPDATE "logs" SET "ip" = '' WHERE "ip" IS NULL;
+
+ALTER TABLE "logs" ALTER "ip" SET NOT NULL;
+
+ALTER TABLE "users" DROP "archived_at";
\ No newline at end of file
@@ -0,0 +1,20 @@
+-- lowercases and removes Estonian diacritics, so searches match with or without them
+CREATE FUNCTION search_normalize(text) RETURNS text
+    LANGUAGE sql IMMUTABLE STRICT PARALLEL SAFE
+    AS $$ SELECT translate(lower($1), 'õäöüšž', 'oaousz') $$;
+
+DROP INDEX trgm_idx_users_name;
+
+CREATE INDEX trgm_idx_users_name ON users USING gin (search_normalize(name) gin_trgm_ops);
+
+CREATE INDEX trgm_idx_users_email ON users USING gin (search_normalize(email::text) gin_trgm_ops);
+
+---- create above / drop below ----
+
+DROP INDEX trgm_idx_users_email;
+
+DROP INDEX trgm_idx_users_name;
+
+CREATE INDEX trgm_idx_users_name ON users USING gin (name gin_trgm_ops);
+
+DROP FUNCTION search_normalize(text);
\ No newline at end of file
@@ -0,0 +1,21 @@
+-- role stays the user's primary role, roles holds all roles space-separated, including role
+ALTER TABLE "users" ADD "roles" text;
+
+UPDATE "users" SET "roles" = "role";
+
+ALTER TABLE "users" ALTER "roles" SET NOT NULL;
+
+ALTER TABLE users
+    ADD CONSTRAINT role_in_roles CHECK (ROLE = ANY (string_to_array(roles, ' ')));
+
+-- students can't have other roles and only students have the student role
+ALTER TABLE users
+    ADD CONSTRAINT student_only_role CHECK ( CASE WHEN ROLE = 'student' THEN
+        roles = 'student'
+    ELSE
+        'student' <> ALL (string_to_array(roles, ' '))
+    END);
+
+---- create above / drop below ----
+
+ALTER TABLE "users" DROP "roles";
\ No newline at end of file
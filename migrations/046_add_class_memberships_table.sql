@@ -0,0 +1,44 @@
+CREATE TABLE "class_memberships" (
+    "id" integer GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    "student_id" integer NOT NULL,
+    "class_id" integer NOT NULL,
+    "year_id" integer NOT NULL,
+    "start_date" date,
+    "end_date" date,
+    "created_at" timestamptz NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE "class_memberships"
+    ADD CONSTRAINT "class_memberships_relation_1" FOREIGN KEY ("student_id") REFERENCES "users" ("id") ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE "class_memberships"
+    ADD CONSTRAINT "class_memberships_relation_2" FOREIGN KEY ("class_id") REFERENCES "classes" ("id") ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE "class_memberships"
+    ADD CONSTRAINT "class_memberships_relation_3" FOREIGN KEY ("year_id") REFERENCES "years" ("id") ON UPDATE CASCADE ON DELETE CASCADE;
+
+-- a missing start or end date means the membership lasts from the start or until the end of the year
+ALTER TABLE "class_memberships"
+    ADD CONSTRAINT "class_memberships_dates" CHECK (start_date < end_date);
+
+CREATE UNIQUE INDEX ON "class_memberships" (student_id, year_id)
+WHERE
+    end_date IS NULL;
+
+CREATE INDEX ON "class_memberships" (class_id, year_id);
+
+-- until now a student was in their current class for every year the class has existed
+INSERT INTO "class_memberships" (student_id, class_id, year_id)
+SELECT
+    users.id,
+    users.class_id,
+    classes_years.year_id
+FROM
+    users
+    INNER JOIN classes_years ON classes_years.class_id = users.class_id
+WHERE
+    users.role = 'student';
+
+---- create above / drop below ----
+
+DROP TABLE "class_memberships";
\ No newline at end of file
@@ -0,0 +1,54 @@
+CREATE TABLE "rollovers" (
+    "id" integer GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    "year_id" integer UNIQUE NOT NULL,
+    "previous_year_id" integer,
+    "rolled_over_by" integer,
+    "at" timestamptz NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE "rollovers"
+    ADD CONSTRAINT "rollovers_relation_1" FOREIGN KEY ("year_id") REFERENCES "years" ("id") ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE "rollovers"
+    ADD CONSTRAINT "rollovers_relation_2" FOREIGN KEY ("previous_year_id") REFERENCES "years" ("id") ON UPDATE CASCADE ON DELETE SET NULL;
+
+ALTER TABLE "rollovers"
+    ADD CONSTRAINT "rollovers_relation_3" FOREIGN KEY ("rolled_over_by") REFERENCES "users" ("id") ON UPDATE CASCADE ON DELETE SET NULL;
+
+-- classes created by the rollover
+CREATE TABLE "rollovers_classes" (
+    "rollover_id" integer NOT NULL,
+    "class_id" integer NOT NULL
+);
+
+ALTER TABLE "rollovers_classes"
+    ADD CONSTRAINT "rollovers_classes_pkey" PRIMARY KEY ("rollover_id", "class_id");
+
+ALTER TABLE "rollovers_classes"
+    ADD CONSTRAINT "rollovers_classes_relation_1" FOREIGN KEY ("rollover_id") REFERENCES "rollovers" ("id") ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE "rollovers_classes"
+    ADD CONSTRAINT "rollovers_classes_relation_2" FOREIGN KEY ("class_id") REFERENCES "classes" ("id") ON UPDATE CASCADE ON DELETE CASCADE;
+
+-- users archived by the rollover
+CREATE TABLE "rollovers_users" (
+    "rollover_id" integer NOT NULL,
+    "user_id" integer NOT NULL
+);
+
+ALTER TABLE "rollovers_users"
+    ADD CONSTRAINT "rollovers_users_pkey" PRIMARY KEY ("rollover_id", "user_id");
+
+ALTER TABLE "rollovers_users"
+    ADD CONSTRAINT "rollovers_users_relation_1" FOREIGN KEY ("rollover_id") REFERENCES "rollovers" ("id") ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE "rollovers_users"
+    ADD CONSTRAINT "rollovers_users_relation_2" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE CASCADE ON DELETE CASCADE;
+
+---- create above / drop below ----
+
+DROP TABLE "rollovers_users";
+
+DROP TABLE "rollovers_classes";
+
+DROP TABLE "rollovers";
\ No newline at end of file